@@ -1,15 +1,53 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jdkingsbury/americano/internal/config"
 	"github.com/jdkingsbury/americano/internal/tui/panes"
 )
 
 func main() {
+	if err := run(); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+// Runs the TUI. Errors are returned rather than exiting, so the deferred cleanup of the
+// layout and the terminal runs first.
+func run() error {
+	maxResultMB := flag.Int64("max-result-mb", panes.DefaultResultMemoryCap>>20, "memory cap in MB for rows fetched into the result pane")
+	configPath := flag.String("config", "", "config file with the saved connections (default $XDG_CONFIG_HOME/americano/config.json)")
+	envFile := flag.String("env-file", ".env", "file with variables for ${VAR} references in connection URLs, skipped when missing")
+	flag.Parse()
+
+	if err := config.LoadEnvFile(*envFile); err != nil {
+		return fmt.Errorf("failed to load env file: %w", err)
+	}
+
+	if *configPath == "" {
+		path, err := config.Path()
+		if err != nil {
+			return err
+		}
+		*configPath = path
+	}
+
+	layout := panes.NewLayoutModel()
+	layout.SetResultMemoryCap(*maxResultMB << 20)
+	layout.SetSecretsPath(config.SecretsPath(*configPath))
+	defer layout.Close()
+
+	// A config that can't be read is left alone rather than overwritten by the next save
+	if err := layout.LoadConnections(*configPath); err != nil {
+		return fmt.Errorf("failed to load saved connections: %w", err)
+	}
+
 	saveState := exec.Command("tput", "smcup")
 	saveState.Stdout = os.Stdout
 	saveState.Run()
@@ -20,10 +58,8 @@ func main() {
 		restoreState.Run()
 	}()
 
-	p := tea.NewProgram(panes.NewLayoutModel(), tea.WithAltScreen())
+	p := tea.NewProgram(layout, tea.WithAltScreen())
 
-	if _, err := p.Run(); err != nil {
-		fmt.Println("Error:", err)
-		os.Exit(1)
-	}
+	_, err := p.Run()
+	return err
 }
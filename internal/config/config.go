@@ -0,0 +1,172 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jdkingsbury/americano/internal/drivers"
+)
+
+/* Saved connections, kept in $XDG_CONFIG_HOME/americano/config.json */
+
+// Version of the config file format written by Save. Load refuses files written by a
+// newer version rather than dropping settings it doesn't know about.
+const Version = 1
+
+const fileName = "config.json"
+
+type Config struct {
+	Version     int          `json:"version"`
+	Connections []Connection `json:"connections"`
+}
+
+// A saved connection profile
+type Connection struct {
+	Name             string   `json:"name"`
+	URL              string   `json:"url"`
+	StatementTimeout Duration `json:"statement_timeout,omitempty"`
+	MaxRows          int      `json:"max_rows,omitempty"`
+	ReadOnly         bool     `json:"read_only,omitempty"`
+}
+
+// A duration written as text such as 30s, so the file stays easy to edit by hand
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q, use a duration such as 30s or 2m", text)
+	}
+
+	*d = Duration(duration)
+	return nil
+}
+
+func NewConnection(name, url string, limits drivers.Limits) Connection {
+	return Connection{
+		Name:             name,
+		URL:              url,
+		StatementTimeout: Duration(limits.StatementTimeout),
+		MaxRows:          limits.MaxRows,
+		ReadOnly:         limits.ReadOnly,
+	}
+}
+
+func (c Connection) Limits() drivers.Limits {
+	return drivers.Limits{
+		StatementTimeout: time.Duration(c.StatementTimeout),
+		MaxRows:          c.MaxRows,
+		ReadOnly:         c.ReadOnly,
+	}
+}
+
+// Directory of the config files, $XDG_CONFIG_HOME/americano or ~/.config/americano
+func Dir() (string, error) {
+	base := os.Getenv("XDG_CONFIG_HOME")
+
+	// Relative paths are invalid per the XDG spec and are ignored
+	if base == "" || !filepath.IsAbs(base) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find the config directory: %w", err)
+		}
+		base = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(base, "americano"), nil
+}
+
+// Path of the config file in Dir
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, fileName), nil
+}
+
+// Reads the config file. A missing file gives an empty config.
+func Load(path string) (Config, error) {
+	config := Config{Version: Version}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	if config.Version > Version {
+		return Config{}, fmt.Errorf("config file %s is version %d, this version of americano reads up to version %d", path, config.Version, Version)
+	}
+
+	// Files from before versioning have no version and the same layout as version 1
+	config.Version = Version
+
+	return config, nil
+}
+
+// Writes the config file atomically. It is written to a temp file in the same
+// directory that replaces the old file once it is complete, so a crash can't
+// leave a half written config behind.
+func Save(path string, config Config) error {
+	config.Version = Version
+	if config.Connections == nil {
+		config.Connections = []Connection{}
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	return writeFileAtomic(path, data)
+}
+
+// URLs may hold passwords, so the file is only readable by the user
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Does nothing once the temp file has been renamed
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+/* Project .env files, read for the ${VAR} references of connection URLs */
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Sets the variables of a .env file that aren't set in the environment already, so
+// CI and shell settings win over the file. A missing file is ignored.
+//
+// Lines are NAME=value and may start with export. Values can be quoted, blank lines
+// and lines starting with # are skipped.
+func LoadEnvFile(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		name = strings.TrimSpace(name)
+		if !ok || !envNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid line %d in %s, use NAME=value", lineNumber, path)
+		}
+
+		value, err := parseEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid value on line %d in %s: %w", lineNumber, path, err)
+		}
+
+		if _, set := os.LookupEnv(name); !set {
+			if err := os.Setenv(name, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Unquotes a value. Double quoted values may hold \n, \" and \\ escapes, single
+// quoted values are taken as they are. Unquoted values end at a # comment.
+func parseEnvValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	switch quote := value[0]; quote {
+	case '"', '\'':
+		end := strings.LastIndexByte(value, quote)
+		if end == 0 {
+			return "", errors.New("missing closing quote")
+		}
+
+		quoted := value[1:end]
+		if quote == '\'' {
+			return quoted, nil
+		}
+		return strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(quoted), nil
+	}
+
+	if comment := strings.Index(value, " #"); comment >= 0 {
+		value = strings.TrimSpace(value[:comment])
+	}
+	return value, nil
+}
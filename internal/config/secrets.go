@@ -0,0 +1,209 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+/* Connection passwords, kept encrypted under a master passphrase next to the config file */
+
+const secretsFileName = "secrets.json"
+
+// Version of the secrets file format, kept apart from the config Version since it is
+// also bound into the encrypted data. OpenSecrets refuses files of a newer version.
+const SecretsVersion = 1
+
+// scrypt cost recommended for interactive use. The values are stored in the file so
+// they can be raised later without breaking older files.
+const (
+	scryptN   = 1 << 15
+	scryptR   = 8
+	scryptP   = 1
+	keyLength = 32
+	saltSize  = 16
+)
+
+// Returned by OpenSecrets when the passphrase doesn't decrypt the secrets file
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+// Layout of the secrets file. The secrets are a JSON object of name to value,
+// encrypted with AES-GCM under a key derived from the passphrase with scrypt.
+type secretsFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Unlocked secrets. Changes are written to the file right away. Safe to use from
+// connections opening in the background.
+type Secrets struct {
+	mu     sync.RWMutex
+	path   string
+	file   secretsFile
+	aead   cipher.AEAD
+	values map[string]string
+}
+
+// Path of the secrets file kept next to the config file at configPath
+func SecretsPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), secretsFileName)
+}
+
+// Whether the secrets file exists yet. Its passphrase is chosen when it is created.
+func SecretsExist(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Decrypts the secrets file with the passphrase. A missing file gives empty secrets
+// that are encrypted with the passphrase once one is set.
+func OpenSecrets(path, passphrase string) (*Secrets, error) {
+	if passphrase == "" {
+		return nil, errors.New("enter a passphrase")
+	}
+
+	secrets := &Secrets{path: path, values: map[string]string{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		secrets.file = secretsFile{Version: SecretsVersion, Salt: salt, N: scryptN, R: scryptR, P: scryptP}
+		secrets.aead, err = newAEAD(passphrase, secrets.file)
+		return secrets, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &secrets.file); err != nil {
+		return nil, fmt.Errorf("invalid secrets file %s: %w", path, err)
+	}
+	if secrets.file.Version > SecretsVersion {
+		return nil, fmt.Errorf("secrets file %s is version %d, this version of americano reads up to version %d", path, secrets.file.Version, SecretsVersion)
+	}
+
+	secrets.aead, err = newAEAD(passphrase, secrets.file)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := secrets.aead.Open(nil, secrets.file.Nonce, secrets.file.Ciphertext, secrets.file.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	if err := json.Unmarshal(plaintext, &secrets.values); err != nil {
+		return nil, fmt.Errorf("invalid secrets in %s: %w", path, err)
+	}
+
+	return secrets, nil
+}
+
+func newAEAD(passphrase string, file secretsFile) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), file.Salt, file.N, file.R, file.P, keyLength)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key derivation settings in the secrets file: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Binds the ciphertext to the key derivation settings, so they can't be swapped out
+func (f secretsFile) additionalData() []byte {
+	return []byte(fmt.Sprintf("americano secrets v%d n=%d r=%d p=%d", f.Version, f.N, f.R, f.P))
+}
+
+func (s *Secrets) Get(name string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.values[name]
+	return value, ok
+}
+
+// Names of the stored secrets, sorted
+func (s *Secrets) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Stores a secret and writes the file
+func (s *Secrets) Set(name, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[name] = value
+	return s.save()
+}
+
+// Removes a secret and writes the file
+func (s *Secrets) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.values[name]; !ok {
+		return nil
+	}
+
+	delete(s.values, name)
+	return s.save()
+}
+
+// Encrypts the secrets with a fresh nonce and writes them atomically
+func (s *Secrets) save() error {
+	plaintext, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+
+	file := s.file
+	file.Version = SecretsVersion
+	file.Nonce = make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Ciphertext = s.aead.Seal(nil, file.Nonce, plaintext, file.additionalData())
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return err
+	}
+
+	s.file = file
+	return nil
+}
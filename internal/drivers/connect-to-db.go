@@ -1,6 +1,8 @@
 package drivers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
@@ -10,46 +12,161 @@ import (
 )
 
 type QueryResultMsg struct {
-	Columns []string
-	Rows    [][]string
-	Error   error
+	// Statement that produced the result when run as part of a script
+	Query      string
+	Columns    []string
+	ColumnInfo []ColumnInfo
+	// Display strings of the typed values in Cells
+	Rows  [][]string
+	Cells [][]Cell
+	// More rows are available than were read. Cursor pages through them when it is set.
+	HasMore bool
+	Cursor  *RowCursor
+	// The row limit of the connection cut the result off
+	Truncated bool
+	// Set for statements such as INSERT, UPDATE, DELETE and DDL that do not return rows
+	IsExec       bool
+	RowsAffected int64
+	LastInsertId int64
+	Error        error
 }
 
+// Returned in QueryResultMsg when a running query is cancelled by the user
+var ErrQueryCancelled = errors.New("query cancelled")
+
+// Every call that talks to the database takes a context so it can be cancelled
 type Database interface {
-	Connect(url string) error
+	Connect(ctx context.Context, url string) error
 	CloseConnection() error
-	ExecuteQuery(query string) QueryResultMsg
-	GetDatabaseName() (string, error)
-	GetTables() ([]string, error)
+	ExecuteQuery(ctx context.Context, query string) QueryResultMsg
+	// Args are keyed by placeholder as returned by Placeholders, e.g. ?1, :name or $1
+	ExecuteQueryWithArgs(ctx context.Context, query string, args map[string]any) QueryResultMsg
+	// Applies the statement timeout and row limit of the connection to the following statements
+	SetLimits(limits Limits)
+	GetDatabaseName(ctx context.Context) (string, error)
+	GetTables(ctx context.Context) ([]TableName, error)
+
+	// Schema introspection of a table listed by GetTables
+	GetColumns(ctx context.Context, table TableName) ([]Column, error)
+	GetIndexes(ctx context.Context, table TableName) ([]Index, error)
+	GetForeignKeys(ctx context.Context, table TableName) ([]ForeignKey, error)
+	GetViews(ctx context.Context) ([]View, error)
+	GetTriggers(ctx context.Context) ([]Trigger, error)
+
+	// Manual commit mode. Statements run in the open transaction until it is committed or
+	// rolled back. Commit and Rollback can't be cancelled, database/sql gives them no context.
+	BeginTransaction(ctx context.Context) error
+	Commit() error
+	Rollback() error
+	InTransaction() bool
 }
 
-func ConnectToDatabase(dbURL string) (Database, tea.Msg) {
+// Checks that a URL has a supported scheme and the shape its driver expects, without
+// connecting. Returns the driver the URL connects with.
+func ValidateURL(dbURL string) (Driver, error) {
+	if strings.TrimSpace(dbURL) == "" {
+		return Driver{}, errors.New("Enter a connection URL")
+	}
+
+	// Values of references are only known when connecting, so only the scheme is checked
+	if hasReferences(dbURL) {
+		scheme, _, ok := strings.Cut(dbURL, "://")
+		if !ok || hasReferences(scheme) {
+			return Driver{}, nil
+		}
+
+		driver, ok := Lookup(scheme)
+		if !ok {
+			return Driver{}, unsupportedSchemeError(scheme)
+		}
+		return driver, nil
+	}
+
 	parsedURL, err := url.Parse(dbURL)
 	if err != nil {
-		return nil, msgtypes.NewErrMsg(fmt.Errorf("Failed to parse URL: %w", err))
+		return Driver{}, fmt.Errorf("Invalid URL: %w", err)
+	}
+	if parsedURL.Scheme == "" {
+		return Driver{}, errors.New("The URL needs a scheme, e.g. sqlite:// or postgres://")
+	}
+
+	driver, ok := Lookup(parsedURL.Scheme)
+	if !ok {
+		return Driver{}, unsupportedSchemeError(parsedURL.Scheme)
+	}
+
+	if driver.ParseURL != nil {
+		if err := driver.ParseURL(dbURL); err != nil {
+			return driver, err
+		}
+	}
+
+	return driver, nil
+}
+
+// Connects to check that a URL works, then closes the connection again.
+// Returns the error that connecting would show.
+func CheckConnection(ctx context.Context, dbURL string, limits Limits) error {
+	db, msg := ConnectToDatabase(ctx, dbURL, limits)
+	if db == nil {
+		if errMsg, ok := msg.(msgtypes.ErrMsg); ok {
+			return errMsg.Err
+		}
+		return errors.New("Failed to connect to the database")
 	}
 
-	var db Database
-	var notification string
+	return db.CloseConnection()
+}
+
+// Connects to the database of a URL and applies the limits of the connection to it.
+// ${VAR} and ${secret:name} references are expanded first and their values never
+// show up in errors.
+func ConnectToDatabase(ctx context.Context, dbURL string, limits Limits) (Database, tea.Msg) {
+	expandedURL, expanded, err := expandReferences(dbURL)
+	if err != nil {
+		return nil, msgtypes.NewErrMsg(err)
+	}
+
+	db, msg := connectToDatabase(ctx, expandedURL, limits)
+	if errMsg, ok := msg.(msgtypes.ErrMsg); ok {
+		msg = msgtypes.NewErrMsg(redactError(errMsg.Err, expanded))
+	}
+
+	return db, msg
+}
+
+func connectToDatabase(ctx context.Context, dbURL string, limits Limits) (Database, tea.Msg) {
+	parsedURL, err := url.Parse(dbURL)
+	if err != nil {
+		return nil, msgtypes.NewErrMsg(fmt.Errorf("Failed to parse URL: %w", err))
+	}
 
 	// Determines the database based on URL scheme
-	switch strings.ToLower(parsedURL.Scheme) {
-	case "postgres", "postgresql":
-		notification = "Connecting to PostgreSQL database..."
-	case "mysql":
-		notification = "Connecting to MySQL database..."
-	case "sqlite":
-		notification = "Connecting to SQLite database..."
-		db = &SQLite{} // Ensure you have your SQLite type defined
-	default:
-		return nil, msgtypes.NewErrMsg(fmt.Errorf("Unsupported database scheme: %s", parsedURL.Scheme))
+	driver, ok := Lookup(parsedURL.Scheme)
+	if !ok {
+		return nil, msgtypes.NewErrMsg(unsupportedSchemeError(parsedURL.Scheme))
+	}
+
+	db := driver.New()
+	notification := fmt.Sprintf("Connecting to %s database...", driver.Name)
+
+	// Read-only connections also ask the database to refuse writes
+	if limits.ReadOnly && len(driver.ReadOnlyParams) > 0 {
+		query := parsedURL.Query()
+		for key, value := range driver.ReadOnlyParams {
+			query.Set(key, value)
+		}
+		parsedURL.RawQuery = query.Encode()
+		dbURL = parsedURL.String()
+		notification = fmt.Sprintf("Connecting to %s database (read-only)...", driver.Name)
 	}
 
 	// Calls connect to establish a connection
-	err = db.Connect(dbURL)
+	err = db.Connect(ctx, dbURL)
 	if err != nil {
 		return nil, msgtypes.NewErrMsg(fmt.Errorf("Failed to connect to the database: %w", err))
 	}
+	db.SetLimits(limits)
 
 	return db, msgtypes.NewNotificationMsg(notification)
 }
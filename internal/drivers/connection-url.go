@@ -0,0 +1,148 @@
+package drivers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Parts of a connection URL that are entered separately. Path is used by drivers
+// with FileFields, the others by drivers with ServerFields.
+type URLParts struct {
+	Path     string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Database string
+	// Query string of driver options, e.g. sslmode=disable&connect_timeout=5
+	Options string
+}
+
+// Assembles a URL for the driver from its parts, escaping each part so passwords and
+// paths may hold characters such as @, / or ?. References such as ${secret:name} are
+// kept as they are.
+func BuildURL(driver Driver, parts URLParts) (string, error) {
+	var tokens referenceTokens
+	dbURL, err := buildURL(driver, parts.apply(tokens.protect))
+
+	return tokens.restore(dbURL), err
+}
+
+func buildURL(driver Driver, parts URLParts) (string, error) {
+	options, err := url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(parts.Options), "?"))
+	if err != nil {
+		return "", fmt.Errorf("Invalid options %q, use name=value pairs joined by &", parts.Options)
+	}
+
+	switch driver.Fields {
+	case FileFields:
+		// Escaped the way parseFileURL unescapes it, so the slashes stay readable
+		path := strings.NewReplacer("%", "%25", "?", "%3F", "#", "%23").Replace(parts.Path)
+		dbURL := driver.Scheme + "://" + path
+		if len(options) > 0 {
+			dbURL += "?" + options.Encode()
+		}
+		return dbURL, nil
+
+	case ServerFields:
+		serverURL := url.URL{
+			Scheme:   driver.Scheme,
+			Host:     parts.Host,
+			RawQuery: options.Encode(),
+		}
+		if parts.Port != "" || strings.Contains(parts.Host, ":") {
+			// Also puts brackets around IPv6 addresses
+			serverURL.Host = net.JoinHostPort(parts.Host, parts.Port)
+			serverURL.Host = strings.TrimSuffix(serverURL.Host, ":")
+		}
+		if parts.Database != "" {
+			serverURL.Path = "/" + parts.Database
+		}
+		if parts.Password != "" {
+			serverURL.User = url.UserPassword(parts.User, parts.Password)
+		} else if parts.User != "" {
+			serverURL.User = url.User(parts.User)
+		}
+		return serverURL.String(), nil
+	}
+
+	return "", fmt.Errorf("The %s driver is connected with a URL only", driver.Name)
+}
+
+// Splits a URL into the parts BuildURL assembles it from
+func SplitURL(driver Driver, dbURL string) (URLParts, error) {
+	var tokens referenceTokens
+	parts, err := splitURL(driver, tokens.protect(dbURL))
+
+	return parts.apply(tokens.restore), err
+}
+
+// Returns the parts with f applied to each of them
+func (p URLParts) apply(f func(string) string) URLParts {
+	return URLParts{
+		Path:     f(p.Path),
+		Host:     f(p.Host),
+		Port:     f(p.Port),
+		User:     f(p.User),
+		Password: f(p.Password),
+		Database: f(p.Database),
+		Options:  f(p.Options),
+	}
+}
+
+func splitURL(driver Driver, dbURL string) (URLParts, error) {
+	var parts URLParts
+
+	switch driver.Fields {
+	case FileFields:
+		rest, ok := cutScheme(dbURL, driver)
+		if !ok {
+			return parts, fmt.Errorf("Invalid %s URL format", driver.Name)
+		}
+
+		path, options, _ := strings.Cut(strings.TrimPrefix(rest, "//"), "?")
+		path, err := url.PathUnescape(path)
+		if err != nil {
+			return parts, fmt.Errorf("Invalid %s path: %w", driver.Name, err)
+		}
+
+		parts.Path = path
+		parts.Options = options
+		return parts, nil
+
+	case ServerFields:
+		serverURL, err := url.Parse(dbURL)
+		if err != nil {
+			return parts, fmt.Errorf("Invalid URL: %w", err)
+		}
+		if _, ok := cutScheme(dbURL, driver); !ok {
+			return parts, fmt.Errorf("Invalid %s URL format", driver.Name)
+		}
+
+		if serverURL.User != nil {
+			parts.User = serverURL.User.Username()
+			parts.Password, _ = serverURL.User.Password()
+		}
+		parts.Host = serverURL.Hostname()
+		parts.Port = serverURL.Port()
+		parts.Database = strings.TrimPrefix(serverURL.Path, "/")
+		parts.Options = serverURL.RawQuery
+		return parts, nil
+	}
+
+	return parts, errors.New("The URL can't be split into fields")
+}
+
+// Returns what follows the scheme: when the URL uses the driver's scheme or an alias
+func cutScheme(dbURL string, driver Driver) (string, bool) {
+	scheme, rest, ok := strings.Cut(dbURL, ":")
+	if !ok {
+		return "", false
+	}
+
+	matched, found := Lookup(scheme)
+	return rest, found && matched.Scheme == driver.Scheme
+}
@@ -0,0 +1,328 @@
+package drivers
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Delimiters tried when sniffing a file, in order of preference on a tie
+var csvDelimiters = []rune{',', '\t', ';', '|'}
+
+// Number of lines looked at to sniff the delimiter
+const sniffLines = 20
+
+// Number of records kept in memory to detect the header and column types, the rest of
+// the file is streamed into the table
+const sampleRecords = 1000
+
+// Loads a csv file into a new table. The delimiter and header are detected unless
+// given in the options, and each column gets INTEGER, REAL or TEXT affinity depending
+// on the values of the first records. Empty fields load as NULL.
+func loadCSV(ctx context.Context, conn *sql.DB, file, table string, options CSVOptions) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+
+	delimiter := options.Delimiter
+	if delimiter == 0 {
+		delimiter = sniffDelimiter(reader, filepath.Ext(file))
+	}
+
+	r := csv.NewReader(reader)
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	records, err := readRecords(r, sampleRecords)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("the file is empty")
+	}
+
+	// A byte order mark would end up in the first column name
+	records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+
+	header := detectHeader(records)
+	if options.Header != nil {
+		header = *options.Header
+	}
+
+	var names []string
+	if header {
+		names = records[0]
+		records = records[1:]
+	}
+	columns := csvColumns(names, records)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	definitions := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, column := range columns {
+		definitions[i] = quoteIdentifier(column.Name) + " " + column.Type
+		placeholders[i] = "?"
+	}
+
+	create := fmt.Sprintf("CREATE TABLE %s (%s);", quoteIdentifier(table), strings.Join(definitions, ", "))
+	if _, err := tx.ExecContext(ctx, create); err != nil {
+		return err
+	}
+
+	insert, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s VALUES (%s);", quoteIdentifier(table), strings.Join(placeholders, ", ")))
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	args := make([]any, len(columns))
+	insertRecord := func(record []string) error {
+		// Short rows are padded with NULLs and fields past the sampled columns are dropped
+		for i, column := range columns {
+			args[i] = nil
+			if i < len(record) {
+				args[i] = column.value(record[i])
+			}
+		}
+
+		_, err := insert.ExecContext(ctx, args...)
+		return err
+	}
+
+	for _, record := range records {
+		if err := insertRecord(record); err != nil {
+			return err
+		}
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := insertRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Reads up to n records, fewer at the end of the file
+func readRecords(r *csv.Reader, n int) ([][]string, error) {
+	var records [][]string
+	for len(records) < n {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// Picks the delimiter that splits the first lines into the same number of fields, the
+// most fields winning a tie. Falls back to tabs for .tsv files and commas otherwise.
+func sniffDelimiter(reader *bufio.Reader, ext string) rune {
+	fallback := ','
+	if strings.EqualFold(ext, ".tsv") || strings.EqualFold(ext, ".tab") {
+		fallback = '\t'
+	}
+
+	// Peek so the sniffed lines are still read as records afterwards
+	sample, err := reader.Peek(64 * 1024)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return fallback
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(sample), "\r\n", "\n"), "\n")
+	// The last line of the sample may be cut off
+	if len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > sniffLines {
+		lines = lines[:sniffLines]
+	}
+
+	best, bestCount := fallback, 0
+	for _, delimiter := range csvDelimiters {
+		count, consistent := -1, true
+		for _, line := range lines {
+			if line == "" {
+				continue
+			}
+
+			n := countUnquoted(line, delimiter)
+			if count == -1 {
+				count = n
+			} else if n != count {
+				consistent = false
+				break
+			}
+		}
+
+		if consistent && count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+
+	return best
+}
+
+// Counts the delimiters of a line outside of double quoted fields
+func countUnquoted(line string, delimiter rune) int {
+	count := 0
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == delimiter && !quoted:
+			count++
+		}
+	}
+
+	return count
+}
+
+// Column of a loaded csv file with its inferred sqlite type
+type csvColumn struct {
+	Name string
+	Type string
+}
+
+// Converts a field into the value inserted for the column
+func (c csvColumn) value(field string) any {
+	if field == "" {
+		return nil
+	}
+
+	switch c.Type {
+	case "INTEGER":
+		if n, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64); err == nil {
+			return n
+		}
+	case "REAL":
+		if f, err := strconv.ParseFloat(strings.TrimSpace(field), 64); err == nil {
+			return f
+		}
+	}
+
+	return field
+}
+
+// Names the columns after the header, or column1, column2... without one, and infers
+// the type of each from its values
+func csvColumns(names []string, records [][]string) []csvColumn {
+	width := len(names)
+	for _, record := range records {
+		width = max(width, len(record))
+	}
+
+	columns := make([]csvColumn, width)
+	taken := map[string]bool{}
+	for i := range columns {
+		name := fmt.Sprintf("column%d", i+1)
+		if i < len(names) && strings.TrimSpace(names[i]) != "" {
+			name = strings.TrimSpace(names[i])
+		}
+
+		columns[i] = csvColumn{Name: uniqueName(name, taken), Type: inferColumnType(records, i)}
+	}
+
+	return columns
+}
+
+// INTEGER when every non-empty value is a whole number, REAL when they are all numbers
+// and TEXT otherwise
+func inferColumnType(records [][]string, column int) string {
+	columnType := ""
+	for _, record := range records {
+		if column >= len(record) || record[column] == "" {
+			continue
+		}
+
+		switch fieldType(record[column]) {
+		case "TEXT":
+			return "TEXT"
+		case "REAL":
+			columnType = "REAL"
+		case "INTEGER":
+			if columnType == "" {
+				columnType = "INTEGER"
+			}
+		}
+	}
+
+	if columnType == "" {
+		return "TEXT"
+	}
+
+	return columnType
+}
+
+func fieldType(field string) string {
+	field = strings.TrimSpace(field)
+
+	// Leading zeros such as zip codes and ids are kept as text
+	if len(field) > 1 && field[0] == '0' && field[1] != '.' {
+		return "TEXT"
+	}
+
+	if _, err := strconv.ParseInt(field, 10, 64); err == nil {
+		return "INTEGER"
+	}
+	// ParseFloat also accepts words such as Inf and NaN
+	if _, err := strconv.ParseFloat(field, 64); err == nil && strings.ContainsAny(field, "0123456789") {
+		return "REAL"
+	}
+
+	return "TEXT"
+}
+
+// Guesses whether the first row holds column names. It does when its values are unique
+// and non-empty, and none of them is a number in a column whose other values are numbers.
+// A file that is text throughout is taken to have a header.
+func detectHeader(records [][]string) bool {
+	first := records[0]
+	seen := map[string]bool{}
+
+	for i, field := range first {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			return false
+		}
+		seen[field] = true
+
+		if len(records) > 1 && fieldType(field) != "TEXT" && inferColumnType(records[1:], i) != "TEXT" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,199 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A folder of csv and tsv files opened as a database. Each file is loaded into a table
+// of an in-memory sqlite database, so changes made in the editor are not written back.
+type CSV struct {
+	SQLite
+	dir string
+}
+
+func init() {
+	Register("csv", Driver{
+		Name:         "CSV files",
+		ExampleURL:   "csv://./exports?delimiter=semicolon&header=false",
+		Capabilities: CapTransactions | CapViewsAndTriggers,
+		Fields:       FileFields,
+		ParseURL: func(dbURL string) error {
+			_, _, err := ParseCSVURL(dbURL)
+			return err
+		},
+		New: func() Database { return &CSV{} },
+	})
+	RegisterAlias("tsv", "csv")
+}
+
+// Extensions of the files loaded as tables
+var csvExtensions = map[string]bool{".csv": true, ".tsv": true, ".tab": true}
+
+// Options given as URL parameters, detected per file when left out
+type CSVOptions struct {
+	Delimiter rune
+	// Whether the first row holds the column names, nil to detect it
+	Header *bool
+}
+
+// Parses csv://dir and tsv://dir URLs with their optional delimiter and header parameters.
+// The path may also point at a single file.
+func ParseCSVURL(dbURL string) (string, CSVOptions, error) {
+	var options CSVOptions
+
+	scheme, _, _ := strings.Cut(dbURL, ":")
+	if !strings.EqualFold(scheme, "csv") && !strings.EqualFold(scheme, "tsv") {
+		return "", options, errors.New("Invalid CSV URL format")
+	}
+
+	fileURL, err := parseFileURL(dbURL, scheme, "CSV")
+	if err != nil {
+		return "", options, err
+	}
+	if fileURL.Memory {
+		return "", options, errors.New("A CSV connection needs a folder or file path")
+	}
+
+	for key := range fileURL.Params {
+		value := fileURL.Params.Get(key)
+
+		switch key {
+		case "delimiter":
+			// Named delimiters, a raw ; isn't allowed in a URL query
+			switch value {
+			case `\t`, "tab":
+				value = "\t"
+			case "semicolon":
+				value = ";"
+			}
+			runes := []rune(value)
+			if len(runes) != 1 {
+				return "", options, fmt.Errorf("Invalid delimiter %q, use a single character", value)
+			}
+			options.Delimiter = runes[0]
+
+		case "header":
+			header, err := strconv.ParseBool(value)
+			if err != nil {
+				return "", options, fmt.Errorf("Invalid header parameter %q, use true or false", value)
+			}
+			options.Header = &header
+
+		default:
+			return "", options, fmt.Errorf("Unknown CSV option %q, use delimiter or header", key)
+		}
+	}
+
+	// A tsv:// URL defaults to tabs
+	if options.Delimiter == 0 && strings.EqualFold(scheme, "tsv") {
+		options.Delimiter = '\t'
+	}
+
+	return fileURL.Path, options, nil
+}
+
+// Loads every csv and tsv file of the folder into an in-memory sqlite database
+func (db *CSV) Connect(ctx context.Context, url string) error {
+	path, options, err := ParseCSVURL(url)
+	if err != nil {
+		return err
+	}
+
+	files, err := csvFiles(path)
+	if err != nil {
+		return err
+	}
+
+	if err := db.SQLite.Connect(ctx, "sqlite::memory:"); err != nil {
+		return err
+	}
+	db.dir = path
+
+	tables := map[string]bool{}
+	for _, file := range files {
+		table := uniqueName(tableNameForFile(file), tables)
+		if err := loadCSV(ctx, db.Connection, file, table, options); err != nil {
+			db.CloseConnection()
+			return fmt.Errorf("Failed to load %s: %w", filepath.Base(file), err)
+		}
+	}
+
+	return nil
+}
+
+// Name of the folder the files were loaded from
+func (db *CSV) GetDatabaseName(ctx context.Context) (string, error) {
+	if db.dir == "" {
+		return "", errors.New("no database connection")
+	}
+
+	return filepath.Base(filepath.Clean(db.dir)), nil
+}
+
+// Lists the csv and tsv files of a folder in name order, or the path itself if it is a file
+func csvFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("The folder %s does not exist.", path)
+		}
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && csvExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No .csv or .tsv files found in %s.", path)
+	}
+
+	return files, nil
+}
+
+// The file name without its extension. Characters other than letters, digits and
+// underscores become underscores, so orders 2024.csv loads as orders_2024.
+func tableNameForFile(file string) string {
+	name := filepath.Base(file)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// Numbers names that are already taken, e.g. orders.csv and orders.tsv load as orders and
+// orders_2. Names are compared case insensitively like sqlite identifiers.
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for i := 2; taken[strings.ToLower(unique)]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	taken[strings.ToLower(unique)] = true
+
+	return unique
+}
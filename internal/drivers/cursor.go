@@ -0,0 +1,243 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// Number of rows read with a query before handing the cursor to the result pane
+const FirstPageSize = 200
+
+// Pulls rows from an open result set a page at a time so large tables are never
+// loaded into memory all at once
+type RowCursor struct {
+	ctx        context.Context
+	rows       *sql.Rows
+	columnInfo []ColumnInfo
+	maxRows    int
+	fetched    int
+	truncated  bool
+	// Frees the statement timeout context of the query once the cursor is closed
+	release func()
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// The cursor a database keeps open for the result pane. Queries set it from the
+// goroutines they run on while CloseConnection, Commit and Rollback close it.
+type cursorHolder struct {
+	mu     sync.Mutex
+	cursor *RowCursor
+}
+
+// Closes the held cursor, if any
+func (h *cursorHolder) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cursor != nil {
+		h.cursor.Close()
+		h.cursor = nil
+	}
+}
+
+// Holds a new cursor, closing one that was set in the meantime
+func (h *cursorHolder) set(cursor *RowCursor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cursor != nil {
+		h.cursor.Close()
+	}
+	h.cursor = cursor
+}
+
+// Implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Runs a statement and returns the first page of rows. When more rows are available the
+// result carries an open cursor. A previously open cursor is closed first, so a reader
+// left open by the result pane can't block the next statement.
+func runQuery(ctx context.Context, conn queryer, query string, dialect Dialect, limits Limits, openCursor *cursorHolder, args ...any) QueryResultMsg {
+	openCursor.close()
+
+	// Writes are refused before they reach the database
+	if limits.ReadOnly {
+		if err := checkReadOnly(query, dialect); err != nil {
+			return QueryResultMsg{Error: err}
+		}
+	}
+
+	// The timeout covers running the statement and reading the first page. Paging
+	// through an open cursor afterwards is up to the user, the cursor releases the
+	// context when it is closed.
+	ctx, stopTimer, release := withStatementTimeout(ctx, limits)
+	defer stopTimer()
+
+	handedOut := false
+	defer func() {
+		if !handedOut {
+			release()
+		}
+	}()
+
+	// Statements without a result set report the rows they affected
+	if !returnsRows(query, dialect) {
+		return execStatement(ctx, conn, query, args...)
+	}
+
+	// Execute the query
+	rowsResult, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return QueryResultMsg{Error: queryError(ctx, err)}
+	}
+
+	cursor, result := newRowCursor(ctx, rowsResult)
+	if result.Error != nil {
+		return result
+	}
+	cursor.maxRows = limits.MaxRows
+
+	result.Rows, result.Cells, result.HasMore, result.Error = cursor.Fetch(FirstPageSize)
+	result.Truncated = cursor.Truncated()
+	if result.HasMore {
+		cursor.release = release
+		handedOut = true
+		result.Cursor = cursor
+		openCursor.set(cursor)
+	}
+
+	return result
+}
+
+func newRowCursor(ctx context.Context, rowsResult *sql.Rows) (*RowCursor, QueryResultMsg) {
+	// Get column names
+	columns, err := rowsResult.Columns()
+	if err != nil {
+		rowsResult.Close()
+		return nil, QueryResultMsg{Error: fmt.Errorf("failed to get columns: %w", err)}
+	}
+
+	columnInfo := scanColumnInfo(rowsResult, columns)
+	cursor := &RowCursor{ctx: ctx, rows: rowsResult, columnInfo: columnInfo}
+
+	return cursor, QueryResultMsg{Columns: columns, ColumnInfo: columnInfo}
+}
+
+// Reads up to n more rows. hasMore is false once the result set is exhausted,
+// the cursor is closed at that point.
+func (c *RowCursor) Fetch(n int) (rows [][]string, cells [][]Cell, hasMore bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, nil, false, nil
+	}
+
+	columns := len(c.columnInfo)
+
+	// Process rows
+	for len(rows) < n {
+		if c.atRowLimit() {
+			return rows, cells, false, nil
+		}
+
+		if !c.rows.Next() {
+			// Check for errors from iterating over rows. Closing releases the context,
+			// so it is checked first.
+			err := c.rows.Err()
+			if err != nil && c.ctx.Err() != nil {
+				err = contextError(c.ctx)
+			} else if err != nil {
+				err = fmt.Errorf("error iterating over rows: %w", err)
+			}
+
+			c.close()
+			return rows, cells, false, err
+		}
+
+		// Create a slice to hold row values
+		values := make([]interface{}, columns)
+		valuePtrs := make([]interface{}, columns)
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		// Scan row values into pointers
+		if err := c.rows.Scan(valuePtrs...); err != nil {
+			c.close()
+			return rows, cells, false, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		// Convert values to typed cells and strings
+		cellRow := make([]Cell, columns)
+		row := make([]string, columns)
+		for i, value := range values {
+			cellRow[i] = newCell(value, c.columnInfo[i].DatabaseType)
+			row[i] = cellRow[i].String()
+		}
+		cells = append(cells, cellRow)
+		rows = append(rows, row)
+		c.fetched++
+	}
+
+	// Report the end of the result with this page rather than an empty next one
+	if c.atRowLimit() {
+		return rows, cells, false, nil
+	}
+
+	return rows, cells, true, nil
+}
+
+// Closes the cursor once the row limit is reached, noting whether rows were cut off.
+// The limit is client side only: no LIMIT is added to the query, the cursor just stops
+// reading, and closing the rows lets the driver discard what the server still sends.
+func (c *RowCursor) atRowLimit() bool {
+	if c.maxRows <= 0 || c.fetched < c.maxRows {
+		return false
+	}
+
+	c.truncated = c.rows.Next()
+	c.close()
+	return true
+}
+
+// Reports whether the row limit of the connection cut off the result
+func (c *RowCursor) Truncated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.truncated
+}
+
+// Closes the underlying result set. Safe to call more than once and while a fetch is running.
+func (c *RowCursor) Close() error {
+	// database/sql allows closing rows while another goroutine is reading them
+	err := c.rows.Close()
+
+	c.mu.Lock()
+	c.closed = true
+	c.releaseContext()
+	c.mu.Unlock()
+
+	return err
+}
+
+func (c *RowCursor) close() {
+	c.rows.Close()
+	c.closed = true
+	c.releaseContext()
+}
+
+func (c *RowCursor) releaseContext() {
+	if c.release != nil {
+		c.release()
+		c.release = nil
+	}
+}
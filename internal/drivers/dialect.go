@@ -0,0 +1,82 @@
+package drivers
+
+import (
+	"strings"
+	"unicode"
+)
+
+/* Lexical rules of each driver's SQL, used to split scripts and find placeholders */
+
+// How a driver's SQL is read. The zero value fits sqlite and duckdb.
+type Dialect struct {
+	Placeholders PlaceholderStyle
+	// A backslash escapes the next character in quoted strings, as in mysql. Postgres
+	// E'...' strings take backslash escapes in every dialect.
+	BackslashEscapes bool
+	// Identifiers are quoted with backticks rather than double quotes, as in mysql
+	BacktickIdentifiers bool
+}
+
+// Quotes an identifier so any name can be used in a statement, e.g. "order" or `order`
+func (d Dialect) QuoteIdentifier(name string) string {
+	if d.BacktickIdentifiers {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return quoteIdentifier(name)
+}
+
+// Quotes a table, or view, with its schema, e.g. "archive"."orders"
+func (d Dialect) QuoteTable(table TableName) string {
+	if table.Schema == "" {
+		return d.QuoteIdentifier(table.Name)
+	}
+	return d.QuoteIdentifier(table.Schema) + "." + d.QuoteIdentifier(table.Name)
+}
+
+// Implemented by databases that know the dialect of their SQL
+type dialecter interface {
+	Dialect() Dialect
+}
+
+// Returns the dialect of a database, the zero Dialect when it doesn't tell
+func DialectOf(db Database) Dialect {
+	if d, ok := db.(dialecter); ok {
+		return d.Dialect()
+	}
+	return Dialect{}
+}
+
+// Returns the index of the quote that closes the string or quoted identifier opened
+// at runes[open], or len(runes) when it isn't closed. Doubled quotes escape
+// themselves, backslashes escape the next character where the dialect allows.
+func closingQuote(runes []rune, open int, dialect Dialect) int {
+	quote := runes[open]
+	backslashes := quote != '`' && (dialect.BackslashEscapes || quote == '\'' && isEscapeString(runes, open))
+
+	for i := open + 1; i < len(runes); i++ {
+		switch {
+		case backslashes && runes[i] == '\\':
+			i++
+		case runes[i] == quote:
+			if i+1 < len(runes) && runes[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+
+	return len(runes)
+}
+
+// Whether the quote at runes[open] starts a postgres E'...' string
+func isEscapeString(runes []rune, open int) bool {
+	if open == 0 || (runes[open-1] != 'E' && runes[open-1] != 'e') {
+		return false
+	}
+	return open == 1 || !isWordRune(runes[open-2])
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,164 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Fetch column details from duckdb_columns() and primary key positions from duckdb_constraints()
+func (db *DuckDB) GetColumns(ctx context.Context, table TableName) ([]Column, error) {
+	schema, name := table.schemaOr("main"), table.Name
+
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT c.column_name, c.data_type, c.is_nullable, c.column_default,
+			COALESCE((
+				SELECT list_position(k.constraint_column_names, c.column_name)
+				FROM duckdb_constraints() k
+				WHERE k.database_name = c.database_name AND k.schema_name = c.schema_name
+					AND k.table_name = c.table_name AND k.constraint_type = 'PRIMARY KEY'
+			), 0)
+		FROM duckdb_columns() c
+		WHERE c.database_name = current_database() AND c.schema_name = ? AND c.table_name = ?
+		ORDER BY c.column_index;`, schema, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var column Column
+		var defaultValue sql.NullString
+		if err := rows.Scan(&column.Name, &column.Type, &column.Nullable, &defaultValue, &column.PrimaryKey); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+
+		column.Default = defaultValue.String
+		column.HasDefault = defaultValue.Valid
+		columns = append(columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// Primary key and unique constraints are backed by indexes, but only CREATE INDEX
+// shows up in duckdb_indexes() so both are listed
+func (db *DuckDB) GetIndexes(ctx context.Context, table TableName) ([]Index, error) {
+	schema, name := table.schemaOr("main"), table.Name
+
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT constraint_name, array_to_string(constraint_column_names, ','),
+			true, constraint_type = 'PRIMARY KEY'
+		FROM duckdb_constraints()
+		WHERE database_name = current_database() AND schema_name = ? AND table_name = ?
+			AND constraint_type IN ('PRIMARY KEY', 'UNIQUE')
+		UNION ALL
+		SELECT index_name, trim(expressions, '[]'), is_unique, is_primary
+		FROM duckdb_indexes()
+		WHERE database_name = current_database() AND schema_name = ? AND table_name = ?
+		ORDER BY 1;`, schema, name, schema, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var index Index
+		var columns sql.NullString
+		if err := rows.Scan(&index.Name, &columns, &index.Unique, &index.Primary); err != nil {
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+
+		// Index expressions are listed as "a, b"
+		for _, column := range splitColumns(columns.String) {
+			index.Columns = append(index.Columns, strings.TrimSpace(column))
+		}
+		indexes = append(indexes, index)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over indexes: %w", err)
+	}
+
+	return indexes, nil
+}
+
+// Fetch foreign keys from duckdb_constraints() and their actions from information_schema
+func (db *DuckDB) GetForeignKeys(ctx context.Context, table TableName) ([]ForeignKey, error) {
+	schema, name := table.schemaOr("main"), table.Name
+
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT k.constraint_name, array_to_string(k.constraint_column_names, ','),
+			k.referenced_table, array_to_string(k.referenced_column_names, ','),
+			COALESCE(r.update_rule, 'NO ACTION'), COALESCE(r.delete_rule, 'NO ACTION')
+		FROM duckdb_constraints() k
+		LEFT JOIN information_schema.referential_constraints r
+			ON r.constraint_catalog = k.database_name AND r.constraint_schema = k.schema_name
+			AND r.constraint_name = k.constraint_name
+		WHERE k.database_name = current_database() AND k.schema_name = ? AND k.table_name = ?
+			AND k.constraint_type = 'FOREIGN KEY'
+		ORDER BY k.constraint_name;`, schema, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var fk ForeignKey
+		var columns, refColumns string
+		if err := rows.Scan(&fk.Name, &columns, &fk.RefTable, &refColumns, &fk.OnUpdate, &fk.OnDelete); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+
+		fk.Columns = splitColumns(columns)
+		fk.RefColumns = splitColumns(refColumns)
+		foreignKeys = append(foreignKeys, fk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over foreign keys: %w", err)
+	}
+
+	return foreignKeys, nil
+}
+
+// Views outside of main carry their schema like their tables
+func (db *DuckDB) GetViews(ctx context.Context) ([]View, error) {
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT CASE WHEN schema_name = 'main' THEN '' ELSE schema_name END, view_name, sql
+		FROM duckdb_views()
+		WHERE database_name = current_database() AND NOT internal AND NOT temporary
+		ORDER BY schema_name, view_name;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch views: %w", err)
+	}
+	defer rows.Close()
+
+	var views []View
+	for rows.Next() {
+		var view View
+		if err := rows.Scan(&view.Schema, &view.Name, &view.Definition); err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+		views = append(views, view)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over views: %w", err)
+	}
+
+	return views, nil
+}
+
+// DuckDB has no triggers
+func (db *DuckDB) GetTriggers(ctx context.Context) ([]Trigger, error) {
+	return nil, nil
+}
@@ -0,0 +1,361 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/marcboeker/go-duckdb"
+)
+
+type DuckDB struct {
+	Connection *sql.DB
+	cursor     cursorHolder
+	tx         transaction
+	limits     Limits
+}
+
+func init() {
+	Register("duckdb", Driver{
+		Name:           "DuckDB",
+		ExampleURL:     "duckdb://./analytics.duckdb?create=true",
+		Capabilities:   CapTransactions | CapViewsAndTriggers,
+		Fields:         FileFields,
+		ReadOnlyParams: map[string]string{"access_mode": "READ_ONLY"},
+		ParseURL: func(dbURL string) error {
+			_, err := ParseDuckDBURL(dbURL)
+			return err
+		},
+		New: func() Database { return &DuckDB{} },
+	})
+}
+
+// Parsed form of a duckdb URL, see FileURL for the accepted forms. The params are
+// passed through as DuckDB settings, e.g. access_mode=read_only or threads=4.
+type DuckDBConfig struct {
+	FileURL
+}
+
+func ParseDuckDBURL(dbURL string) (DuckDBConfig, error) {
+	fileURL, err := parseFileURL(dbURL, "duckdb", "DuckDB")
+	return DuckDBConfig{fileURL}, err
+}
+
+// go-duckdb data source name for the config, an empty path opens an in-memory database
+func (c DuckDBConfig) DSN() string {
+	var dsn string
+	params := c.Params
+	if !c.Memory {
+		dsn = c.Path
+	} else if params.Has("access_mode") {
+		// An in-memory database can't be opened read-only, the statement check of
+		// read-only connections still refuses writes
+		params = maps.Clone(params)
+		params.Del("access_mode")
+	}
+
+	if len(params) > 0 {
+		dsn += "?" + params.Encode()
+	}
+
+	return dsn
+}
+
+// Opens a duckdb database file or an in-memory database. Every pooled connection shares
+// the one database, so tables created in the editor show up in the tree.
+func (db *DuckDB) Connect(ctx context.Context, url string) error {
+	config, err := ParseDuckDBURL(url)
+	if err != nil {
+		return err
+	}
+
+	if err := config.checkExists(); err != nil {
+		return err
+	}
+
+	conn, err := sql.Open("duckdb", config.DSN())
+	if err != nil {
+		return err
+	}
+
+	// Test Connection
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return fmt.Errorf("Failed to ping the database: %w", err)
+	}
+
+	// Assign the connection to the DuckDB struct
+	db.Connection = conn
+
+	return nil
+}
+
+// Execute db query. Large results keep a cursor open for the result pane to page through.
+func (db *DuckDB) ExecuteQuery(ctx context.Context, query string) QueryResultMsg {
+	return runQuery(ctx, db.tx.queryer(db.Connection), query, db.Dialect(), db.limits, &db.cursor)
+}
+
+// Uses the default dialect
+func (db *DuckDB) Dialect() Dialect {
+	return Dialect{}
+}
+
+// Execute db query with bind args for its ?, :name or $1 placeholders
+func (db *DuckDB) ExecuteQueryWithArgs(ctx context.Context, query string, args map[string]any) QueryResultMsg {
+	return runQueryWithArgs(ctx, db.tx.queryer(db.Connection), query, args, db.Dialect(), db.limits, &db.cursor)
+}
+
+// Shows the operators of EXPLAIN (FORMAT JSON), flagging sequential scans
+func (db *DuckDB) ExplainQuery(ctx context.Context, query string, args map[string]any) QueryPlanMsg {
+	ctx, _, release := withStatementTimeout(ctx, db.limits)
+	defer release()
+
+	rows, err := explainRows(ctx, db.tx.queryer(db.Connection), "EXPLAIN (FORMAT JSON)", query, args, db.Dialect(), &db.cursor)
+	if err != nil {
+		return QueryPlanMsg{Query: query, Error: err}
+	}
+	defer rows.Close()
+
+	var nodes []PlanNode
+	for rows.Next() {
+		var key, plan string
+		if err := rows.Scan(&key, &plan); err != nil {
+			return QueryPlanMsg{Query: query, Error: err}
+		}
+
+		var operators []duckDBPlanOperator
+		if err := json.Unmarshal([]byte(plan), &operators); err != nil {
+			return QueryPlanMsg{Query: query, Error: fmt.Errorf("failed to read the query plan: %w", err)}
+		}
+		for _, operator := range operators {
+			nodes = operator.appendTo(nodes, 0)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return QueryPlanMsg{Query: query, Error: queryError(ctx, err)}
+	}
+
+	return QueryPlanMsg{Query: query, Nodes: nodes}
+}
+
+// Operator of a duckdb JSON plan, e.g. {"name": "SEQ_SCAN ", "extra_info": {"Text": "users"}}
+type duckDBPlanOperator struct {
+	Name      string               `json:"name"`
+	Children  []duckDBPlanOperator `json:"children"`
+	ExtraInfo map[string]any       `json:"extra_info"`
+}
+
+// Flattens the operator and its children into plan steps
+func (o duckDBPlanOperator) appendTo(nodes []PlanNode, parent int) []PlanNode {
+	name := strings.TrimSpace(o.Name)
+	detail := name
+	if table, ok := o.ExtraInfo["Text"].(string); ok {
+		detail += " " + table
+	}
+
+	// Projections are left out, they are long and rarely explain a slow query
+	var details []string
+	for key, value := range o.ExtraInfo {
+		if key == "Text" || key == "Projections" {
+			continue
+		}
+		if values, ok := value.([]any); ok {
+			parts := make([]string, len(values))
+			for i, v := range values {
+				parts[i] = fmt.Sprint(v)
+			}
+			value = strings.Join(parts, ", ")
+		}
+		details = append(details, fmt.Sprintf("%s: %v", key, value))
+	}
+	sort.Strings(details)
+	if len(details) > 0 {
+		detail += " (" + strings.Join(details, "; ") + ")"
+	}
+
+	warning := ""
+	if name == "SEQ_SCAN" {
+		warning = PlanFullTableScan
+	}
+
+	id := len(nodes) + 1
+	nodes = append(nodes, PlanNode{ID: id, Parent: parent, Detail: detail, Warning: warning})
+	for _, child := range o.Children {
+		nodes = child.appendTo(nodes, id)
+	}
+
+	return nodes
+}
+
+func (db *DuckDB) SetLimits(limits Limits) {
+	db.limits = limits
+}
+
+// Close connection to duckdb database
+func (db *DuckDB) CloseConnection() error {
+	// An open transaction is rolled back rather than left on a closed pool
+	if db.tx.open() {
+		db.tx.end(false, &db.cursor)
+	}
+
+	db.cursor.close()
+
+	if db.Connection != nil {
+		return db.Connection.Close()
+	}
+
+	return nil
+}
+
+// Starts a transaction that the following statements run in until Commit or Rollback
+func (db *DuckDB) BeginTransaction(ctx context.Context) error {
+	return db.tx.begin(ctx, db.Connection)
+}
+
+func (db *DuckDB) Commit() error {
+	return db.tx.end(true, &db.cursor)
+}
+
+func (db *DuckDB) Rollback() error {
+	return db.tx.end(false, &db.cursor)
+}
+
+func (db *DuckDB) InTransaction() bool {
+	return db.tx.open()
+}
+
+// Name of the catalog, the file name without its extension or memory
+func (db *DuckDB) GetDatabaseName(ctx context.Context) (string, error) {
+	if db.Connection == nil {
+		return "", errors.New("no database connection")
+	}
+
+	var dbName string
+	if err := db.Connection.QueryRowContext(ctx, "SELECT current_database();").Scan(&dbName); err != nil {
+		return "", fmt.Errorf("failed to fetch database name: %w", err)
+	}
+
+	return dbName, nil
+}
+
+// Fetch table names of the open database. Tables outside of main carry their schema.
+func (db *DuckDB) GetTables(ctx context.Context) ([]TableName, error) {
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT schema_name, table_name
+		FROM duckdb_tables()
+		WHERE database_name = current_database() AND NOT internal AND NOT temporary
+		ORDER BY schema_name, table_name;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []TableName
+	for rows.Next() {
+		var schema, table string
+		if err := rows.Scan(&schema, &table); err != nil {
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+
+		tables = append(tables, newTableName(schema, table, "main"))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over tables: %w", err)
+	}
+
+	return tables, nil
+}
+
+func (db *DuckDB) DefaultSchema() string {
+	return "main"
+}
+
+// Converts the values go-duckdb scans into its own types, ok is false for other values
+func duckDBCell(value interface{}) (Cell, bool) {
+	switch v := value.(type) {
+	case duckdb.Decimal:
+		return Cell{Kind: NumberCell, Value: formatDecimal(v)}, true
+	case *big.Int:
+		// HUGEINT and UHUGEINT
+		return Cell{Kind: NumberCell, Value: v.String()}, true
+	case duckdb.Interval:
+		return Cell{Kind: TextCell, Value: formatInterval(v)}, true
+	}
+
+	return Cell{}, false
+}
+
+// Decimals are kept as strings so no precision is lost, e.g. 12345 with scale 2 is 123.45
+func formatDecimal(d duckdb.Decimal) string {
+	if d.Value == nil {
+		return "0"
+	}
+
+	digits := new(big.Int).Abs(d.Value).String()
+	sign := ""
+	if d.Value.Sign() < 0 {
+		sign = "-"
+	}
+
+	scale := int(d.Scale)
+	if scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+// Formats an interval the way duckdb prints it, e.g. 1 year 2 months 3 days 04:05:06
+func formatInterval(i duckdb.Interval) string {
+	var parts []string
+
+	plural := func(n int64, unit string) string {
+		if n == 1 || n == -1 {
+			return fmt.Sprintf("%d %s", n, unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	if years := int64(i.Months / 12); years != 0 {
+		parts = append(parts, plural(years, "year"))
+	}
+	if months := int64(i.Months % 12); months != 0 {
+		parts = append(parts, plural(months, "month"))
+	}
+	if i.Days != 0 {
+		parts = append(parts, plural(int64(i.Days), "day"))
+	}
+
+	if i.Micros != 0 || len(parts) == 0 {
+		micros := i.Micros
+		sign := ""
+		if micros < 0 {
+			sign = "-"
+			micros = -micros
+		}
+
+		clock := fmt.Sprintf("%s%02d:%02d:%02d", sign, micros/3_600_000_000, micros/60_000_000%60, micros/1_000_000%60)
+		if fraction := micros % 1_000_000; fraction != 0 {
+			clock += strings.TrimRight(fmt.Sprintf(".%06d", fraction), "0")
+		}
+		parts = append(parts, clock)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Formats 16 raw uuid bytes as 8-4-4-4-12 hex groups
+func formatUUID(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
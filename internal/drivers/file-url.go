@@ -0,0 +1,92 @@
+package drivers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Location of a file based database such as sqlite or duckdb. Accepted forms:
+//
+//	sqlite::memory:                      in-memory database
+//	sqlite:///var/data/app.db            absolute path
+//	sqlite://./dev.db, sqlite://dev.db   path relative to the working directory
+//	sqlite://./dev.db?mode=ro&create=true
+type FileURL struct {
+	// Path of the database file, relative paths are relative to the working directory
+	Path   string
+	Memory bool
+	// Create the file when it does not exist, set with ?create=true
+	Create bool
+	// Remaining query parameters, passed through to the driver
+	Params url.Values
+}
+
+// Parses a file URL for the given scheme, name is used in error messages
+func parseFileURL(dbURL, scheme, name string) (FileURL, error) {
+	var fileURL FileURL
+
+	urlScheme, rest, ok := strings.Cut(dbURL, ":")
+	if !ok || !strings.EqualFold(urlScheme, scheme) {
+		return fileURL, fmt.Errorf("Invalid %s URL format", name)
+	}
+
+	rest, query, _ := strings.Cut(rest, "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return fileURL, fmt.Errorf("Invalid %s URL parameters: %w", name, err)
+	}
+
+	if create := params.Get("create"); create != "" {
+		fileURL.Create, err = strconv.ParseBool(create)
+		if err != nil {
+			return fileURL, fmt.Errorf("Invalid create parameter %q, use true or false", create)
+		}
+		params.Del("create")
+	}
+	fileURL.Params = params
+
+	// scheme:///abs/path keeps its leading slash, scheme://rel/path and scheme:rel/path are relative
+	path := strings.TrimPrefix(rest, "//")
+	if path == ":memory:" {
+		fileURL.Memory = true
+		return fileURL, nil
+	}
+
+	fileURL.Path, err = url.PathUnescape(path)
+	if err != nil {
+		return fileURL, fmt.Errorf("Invalid %s path: %w", name, err)
+	}
+
+	if fileURL.Path == "" {
+		return fileURL, errors.New("The database file path cannot be empty.")
+	}
+
+	return fileURL, nil
+}
+
+// A missing file is only created when asked for, so a typo in the path isn't silently
+// turned into a new empty database
+func (u FileURL) checkExists() error {
+	if u.Memory || u.Create {
+		return nil
+	}
+
+	if _, err := os.Stat(u.Path); os.IsNotExist(err) {
+		return fmt.Errorf("The database file %s does not exist. Add ?create=true to the URL to create it.", u.Path)
+	}
+
+	return nil
+}
+
+// Copy of the params that the driver specific DSN can add to
+func (u FileURL) params() url.Values {
+	params := url.Values{}
+	for key, values := range u.Params {
+		params[key] = values
+	}
+	return params
+}
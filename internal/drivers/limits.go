@@ -0,0 +1,43 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Safety settings of a connection, applied to every statement it runs
+type Limits struct {
+	// Statements still running after this long are cancelled, 0 for no timeout
+	StatementTimeout time.Duration
+	// Results are cut off after this many rows, 0 for no limit. The cap is applied
+	// while reading, the query itself is sent unchanged, so the database still plans
+	// and may compute the whole result.
+	MaxRows int
+	// Only statements that read data are run, and the database is opened read-only
+	// where the driver supports it
+	ReadOnly bool
+}
+
+// Returned in QueryResultMsg when a statement runs longer than the connection allows
+var ErrStatementTimeout = errors.New("statement timeout exceeded")
+
+// Returned in QueryResultMsg when a read-only connection is asked to run a write statement
+var ErrReadOnly = errors.New("read-only connection")
+
+// Cancels the context with ErrStatementTimeout once the statement timeout of the limits
+// runs out. stopTimer ends the timeout while the context stays usable, release frees
+// the context once nothing reads with it anymore and also stops the timer.
+func withStatementTimeout(ctx context.Context, limits Limits) (timeoutCtx context.Context, stopTimer func(), release func()) {
+	if limits.StatementTimeout <= 0 {
+		return ctx, func() {}, func() {}
+	}
+
+	timeoutCtx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(limits.StatementTimeout, func() { cancel(ErrStatementTimeout) })
+
+	return timeoutCtx, func() { timer.Stop() }, func() {
+		timer.Stop()
+		cancel(nil)
+	}
+}
@@ -0,0 +1,176 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Fetch column details for a table in the selected database from information_schema
+func (db *MySQL) GetColumns(ctx context.Context, table TableName) ([]Column, error) {
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT c.column_name, c.column_type, c.is_nullable = 'YES', c.column_default,
+			COALESCE(k.ordinal_position, 0)
+		FROM information_schema.columns c
+		LEFT JOIN information_schema.key_column_usage k
+			ON k.table_schema = c.table_schema AND k.table_name = c.table_name
+			AND k.column_name = c.column_name AND k.constraint_name = 'PRIMARY'
+		WHERE c.table_schema = DATABASE() AND c.table_name = ?
+		ORDER BY c.ordinal_position;`, table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var column Column
+		var defaultValue sql.NullString
+		if err := rows.Scan(&column.Name, &column.Type, &column.Nullable, &defaultValue, &column.PrimaryKey); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+
+		column.Default = defaultValue.String
+		column.HasDefault = defaultValue.Valid
+		columns = append(columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// Fetch indexes from information_schema.statistics with their columns in key order
+func (db *MySQL) GetIndexes(ctx context.Context, table TableName) ([]Index, error) {
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT index_name, MIN(non_unique) = 0,
+			GROUP_CONCAT(COALESCE(column_name, '<expression>') ORDER BY seq_in_index SEPARATOR ',')
+		FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = ?
+		GROUP BY index_name
+		ORDER BY index_name;`, table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var index Index
+		var columns string
+		if err := rows.Scan(&index.Name, &index.Unique, &columns); err != nil {
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+
+		index.Primary = index.Name == "PRIMARY"
+		index.Columns = splitColumns(columns)
+		indexes = append(indexes, index)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over indexes: %w", err)
+	}
+
+	return indexes, nil
+}
+
+// Fetch foreign key constraints from key_column_usage and referential_constraints
+func (db *MySQL) GetForeignKeys(ctx context.Context, table TableName) ([]ForeignKey, error) {
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT k.constraint_name,
+			GROUP_CONCAT(k.column_name ORDER BY k.ordinal_position SEPARATOR ','),
+			k.referenced_table_name,
+			GROUP_CONCAT(k.referenced_column_name ORDER BY k.ordinal_position SEPARATOR ','),
+			r.update_rule, r.delete_rule
+		FROM information_schema.key_column_usage k
+		JOIN information_schema.referential_constraints r
+			ON r.constraint_schema = k.constraint_schema AND r.constraint_name = k.constraint_name
+		WHERE k.table_schema = DATABASE() AND k.table_name = ?
+			AND k.referenced_table_name IS NOT NULL
+		GROUP BY k.constraint_name, k.referenced_table_name, r.update_rule, r.delete_rule
+		ORDER BY k.constraint_name;`, table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var fk ForeignKey
+		var columns, refColumns string
+		if err := rows.Scan(&fk.Name, &columns, &fk.RefTable, &refColumns, &fk.OnUpdate, &fk.OnDelete); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+
+		fk.Columns = splitColumns(columns)
+		fk.RefColumns = splitColumns(refColumns)
+		foreignKeys = append(foreignKeys, fk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over foreign keys: %w", err)
+	}
+
+	return foreignKeys, nil
+}
+
+func (db *MySQL) GetViews(ctx context.Context) ([]View, error) {
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT table_name, view_definition
+		FROM information_schema.views
+		WHERE table_schema = DATABASE()
+		ORDER BY table_name;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch views: %w", err)
+	}
+	defer rows.Close()
+
+	var views []View
+	for rows.Next() {
+		var view View
+		if err := rows.Scan(&view.Name, &view.Definition); err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+		views = append(views, view)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over views: %w", err)
+	}
+
+	return views, nil
+}
+
+// information_schema only keeps the trigger body, so the CREATE statement is rebuilt from its parts
+func (db *MySQL) GetTriggers(ctx context.Context) ([]Trigger, error) {
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT trigger_name, event_object_table, action_timing, event_manipulation, action_statement
+		FROM information_schema.triggers
+		WHERE trigger_schema = DATABASE()
+		ORDER BY trigger_name;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch triggers: %w", err)
+	}
+	defer rows.Close()
+
+	var triggers []Trigger
+	for rows.Next() {
+		var trigger Trigger
+		var timing, event, statement string
+		if err := rows.Scan(&trigger.Name, &trigger.Table, &timing, &event, &statement); err != nil {
+			return nil, fmt.Errorf("failed to scan trigger: %w", err)
+		}
+
+		trigger.Definition = fmt.Sprintf("CREATE TRIGGER %s %s %s ON %s FOR EACH ROW %s",
+			trigger.Name, timing, event, trigger.Table, statement)
+		triggers = append(triggers, trigger)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over triggers: %w", err)
+	}
+
+	return triggers, nil
+}
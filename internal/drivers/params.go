@@ -0,0 +1,209 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Which placeholders a driver understands
+type PlaceholderStyle int
+
+const (
+	// ?, ?NNN, :name and $1, bound as a plain ? per occurrence. Used by sqlite, mysql
+	// and duckdb.
+	QuestionPlaceholders PlaceholderStyle = iota
+	// $1 and :name, bound as $1, $2... A ? is left alone, postgres uses it in the jsonb
+	// operators ?, ?| and ?&.
+	DollarPlaceholders
+)
+
+// A ?, ?NNN, :name or $1 placeholder. Key identifies the value bound to it: anonymous
+// ? placeholders are numbered in order, so the second ? in a query has the key ?2.
+type placeholder struct {
+	start, end int // Rune offsets in the query
+	key        string
+	anonymous  bool
+}
+
+// Returns the keys of the placeholders in a query in order of first appearance,
+// e.g. [?1 ?2] for "a = ? AND b = ?" and [:id] for "id = :id OR parent = :id"
+func Placeholders(query string, dialect Dialect) []string {
+	var keys []string
+	seen := map[string]bool{}
+
+	for _, p := range scanPlaceholders(query, dialect, 0) {
+		if !seen[p.key] {
+			seen[p.key] = true
+			keys = append(keys, p.key)
+		}
+	}
+
+	return keys
+}
+
+// Finds the placeholders of a dialect outside of string literals, quoted identifiers
+// and comments. Anonymous placeholders are numbered after the given count, which lets
+// a script number them across statements.
+func scanPlaceholders(query string, dialect Dialect, anonymous int) []placeholder {
+	var placeholders []placeholder
+	runes := []rune(query)
+
+	// Reads the run of digits or name characters that starts at i
+	readWhile := func(i int, ok func(rune) bool) int {
+		for i < len(runes) && ok(runes[i]) {
+			i++
+		}
+		return i
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			// Line comment
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// Block comment
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+
+		case r == '\'' || r == '"' || r == '`':
+			// Quoted strings and identifiers
+			i = closingQuote(runes, i, dialect)
+
+		case r == '$':
+			if tag, ok := dollarQuoteTag(runes[i:]); ok {
+				// Skip to the closing tag of a dollar quoted string
+				i += len(tag)
+				for i < len(runes) && !hasRunePrefix(runes[i:], tag) {
+					i++
+				}
+				i += len(tag) - 1
+				continue
+			}
+
+			end := readWhile(i+1, unicode.IsDigit)
+			if end > i+1 {
+				placeholders = append(placeholders, placeholder{start: i, end: end, key: string(runes[i:end])})
+				i = end - 1
+			}
+
+		case r == '?' && dialect.Placeholders == QuestionPlaceholders:
+			end := readWhile(i+1, unicode.IsDigit)
+			if end > i+1 {
+				// ?NNN, later anonymous placeholders continue after the largest number
+				n, _ := strconv.Atoi(string(runes[i+1 : end]))
+				anonymous = max(anonymous, n)
+				placeholders = append(placeholders, placeholder{start: i, end: end, key: "?" + strconv.Itoa(n)})
+			} else {
+				anonymous++
+				placeholders = append(placeholders, placeholder{start: i, end: end, key: "?" + strconv.Itoa(anonymous), anonymous: true})
+			}
+			i = end - 1
+
+		case r == ':':
+			// Not a postgres cast (::) or an assignment (:=)
+			if i+1 >= len(runes) || !(unicode.IsLetter(runes[i+1]) || runes[i+1] == '_') {
+				continue
+			}
+			if i > 0 && (runes[i-1] == ':' || isWordRune(runes[i-1])) {
+				continue
+			}
+
+			end := readWhile(i+1, isWordRune)
+			placeholders = append(placeholders, placeholder{start: i, end: end, key: string(runes[i:end])})
+			i = end - 1
+
+		case isWordRune(r):
+			// Skip the rest of the word so a $ or ? inside an identifier is left alone
+			i = readWhile(i, func(r rune) bool { return isWordRune(r) || r == '$' }) - 1
+		}
+	}
+
+	return placeholders
+}
+
+// Rewrites the placeholders of a query into the style the driver understands and
+// returns the args in bind order
+func bindPlaceholders(query string, args map[string]any, dialect Dialect) (string, []any, error) {
+	placeholders := scanPlaceholders(query, dialect, 0)
+	if len(placeholders) == 0 {
+		return query, nil, nil
+	}
+
+	runes := []rune(query)
+	var b strings.Builder
+	var bindArgs []any
+	positions := map[string]int{}
+	last := 0
+
+	for _, p := range placeholders {
+		value, ok := args[p.key]
+		if !ok {
+			return "", nil, fmt.Errorf("no value given for placeholder %s", p.key)
+		}
+
+		b.WriteString(string(runes[last:p.start]))
+		last = p.end
+
+		if dialect.Placeholders == QuestionPlaceholders {
+			b.WriteString("?")
+			bindArgs = append(bindArgs, value)
+			continue
+		}
+
+		position, seen := positions[p.key]
+		if !seen {
+			bindArgs = append(bindArgs, value)
+			position = len(bindArgs)
+			positions[p.key] = position
+		}
+		b.WriteString("$" + strconv.Itoa(position))
+	}
+	b.WriteString(string(runes[last:]))
+
+	return b.String(), bindArgs, nil
+}
+
+// Runs a statement with bind args for its placeholders
+func runQueryWithArgs(ctx context.Context, conn queryer, query string, args map[string]any, dialect Dialect, limits Limits, openCursor *cursorHolder) QueryResultMsg {
+	boundQuery, bindArgs, err := bindPlaceholders(query, args, dialect)
+	if err != nil {
+		return QueryResultMsg{Error: err}
+	}
+
+	return runQuery(ctx, conn, boundQuery, dialect, limits, openCursor, bindArgs...)
+}
+
+// Converts a value typed into the parameter form into a bind arg. NULL binds a null,
+// numbers bind as numbers and 'quoted' values always bind as text.
+func ParseParamValue(value string) any {
+	trimmed := strings.TrimSpace(value)
+
+	switch {
+	case strings.EqualFold(trimmed, "NULL"):
+		return nil
+	case len(trimmed) >= 2 && strings.HasPrefix(trimmed, "'") && strings.HasSuffix(trimmed, "'"):
+		return strings.ReplaceAll(trimmed[1:len(trimmed)-1], "''", "'")
+	}
+
+	if n, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+		return n
+	}
+	// ParseFloat also accepts words such as Inf and NaN
+	if f, err := strconv.ParseFloat(trimmed, 64); err == nil && strings.ContainsAny(trimmed, "0123456789") {
+		return f
+	}
+
+	return value
+}
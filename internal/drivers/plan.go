@@ -0,0 +1,139 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// One step of a query plan. Steps at the top of the plan have Parent 0.
+type PlanNode struct {
+	ID     int
+	Parent int
+	Detail string
+	// Why the step is worth a closer look, e.g. a full table scan. Empty otherwise.
+	Warning string
+}
+
+type QueryPlanMsg struct {
+	Query string
+	Nodes []PlanNode
+	Error error
+}
+
+// Warnings given to plan steps
+const (
+	PlanFullTableScan = "full table scan"
+	PlanTempBTree     = "temp B-tree"
+	PlanTempTable     = "temporary table"
+)
+
+// Implemented by databases that can show how they would run a query without running it.
+// Args are keyed by placeholder like in ExecuteQueryWithArgs.
+type Explainer interface {
+	ExplainQuery(ctx context.Context, query string, args map[string]any) QueryPlanMsg
+}
+
+// Runs the explain statement of a query, e.g. EXPLAIN QUERY PLAN SELECT ... An open
+// cursor is closed first, like runQuery does. ExplainQuery applies the statement timeout
+// to ctx, so it also covers reading the plan.
+func explainRows(ctx context.Context, conn queryer, explain, query string, args map[string]any, dialect Dialect, openCursor *cursorHolder) (*sql.Rows, error) {
+	openCursor.close()
+
+	boundQuery, bindArgs, err := bindPlaceholders(query, args, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, explain+" "+strings.TrimSuffix(strings.TrimSpace(boundQuery), ";"), bindArgs...)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	return rows, nil
+}
+
+// Runs an explain statement that returns its plan as lines of text
+func explainText(ctx context.Context, conn queryer, explain, query string, args map[string]any, dialect Dialect, openCursor *cursorHolder) (string, error) {
+	rows, err := explainRows(ctx, conn, explain, query, args, dialect, openCursor)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", queryError(ctx, err)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// Reads plans that are returned as indented text, one step per line. Lines starting
+// with -> are steps nested by their indentation, the other lines add details to the
+// step above them, e.g.
+//
+//	Sort  (cost=...)
+//	  Sort Key: name
+//	  ->  Seq Scan on users  (cost=...)
+func parseIndentedPlan(text string, warn func(string) string) []PlanNode {
+	type level struct {
+		indent int
+		id     int
+	}
+
+	var nodes []PlanNode
+	var stack []level
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		isStep := len(nodes) == 0 || strings.HasPrefix(trimmed, "->")
+
+		if !isStep {
+			last := &nodes[len(nodes)-1]
+			last.Detail += "; " + trimmed
+			continue
+		}
+
+		detail := strings.TrimSpace(strings.TrimPrefix(trimmed, "->"))
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		parent := 0
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1].id
+		}
+
+		id := len(nodes) + 1
+		nodes = append(nodes, PlanNode{ID: id, Parent: parent, Detail: detail, Warning: warn(detail)})
+		stack = append(stack, level{indent: indent, id: id})
+	}
+
+	return nodes
+}
+
+// Number of steps that have a warning
+func PlanWarnings(nodes []PlanNode) int {
+	count := 0
+	for _, node := range nodes {
+		if node.Warning != "" {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,207 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Referential actions as stored in pg_constraint
+var postgresFKActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+// Fetch column details from information_schema
+func (db *Postgres) GetColumns(ctx context.Context, table TableName) ([]Column, error) {
+	schema, name := table.schemaOr("public"), table.Name
+
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT c.column_name, c.data_type, c.is_nullable = 'YES', c.column_default,
+			COALESCE(k.ordinal_position, 0)
+		FROM information_schema.columns c
+		LEFT JOIN information_schema.table_constraints t
+			ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+			AND t.constraint_type = 'PRIMARY KEY'
+		LEFT JOIN information_schema.key_column_usage k
+			ON k.constraint_schema = t.constraint_schema AND k.constraint_name = t.constraint_name
+			AND k.column_name = c.column_name
+		WHERE c.table_schema = $1 AND c.table_name = $2
+		ORDER BY c.ordinal_position;`, schema, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var column Column
+		var defaultValue sql.NullString
+		if err := rows.Scan(&column.Name, &column.Type, &column.Nullable, &defaultValue, &column.PrimaryKey); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+
+		column.Default = defaultValue.String
+		column.HasDefault = defaultValue.Valid
+		columns = append(columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// Fetch indexes from pg_index with their columns in key order
+func (db *Postgres) GetIndexes(ctx context.Context, table TableName) ([]Index, error) {
+	schema, name := table.schemaOr("public"), table.Name
+
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT i.relname, ix.indisunique, ix.indisprimary,
+			COALESCE(string_agg(a.attname, ',' ORDER BY k.ord), '')
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord)
+		LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE n.nspname = $1 AND t.relname = $2
+		GROUP BY i.relname, ix.indisunique, ix.indisprimary
+		ORDER BY i.relname;`, schema, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var index Index
+		var columns string
+		if err := rows.Scan(&index.Name, &index.Unique, &index.Primary, &columns); err != nil {
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+
+		index.Columns = splitColumns(columns)
+		indexes = append(indexes, index)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over indexes: %w", err)
+	}
+
+	return indexes, nil
+}
+
+// Fetch foreign key constraints from pg_constraint
+func (db *Postgres) GetForeignKeys(ctx context.Context, table TableName) ([]ForeignKey, error) {
+	schema, name := table.schemaOr("public"), table.Name
+
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT c.conname,
+			(SELECT string_agg(a.attname, ',' ORDER BY k.ord)
+				FROM unnest(c.conkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum),
+			CASE WHEN rn.nspname = 'public' THEN r.relname ELSE rn.nspname || '.' || r.relname END,
+			(SELECT string_agg(a.attname, ',' ORDER BY k.ord)
+				FROM unnest(c.confkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.attnum),
+			c.confupdtype, c.confdeltype
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class r ON r.oid = c.confrelid
+		JOIN pg_namespace rn ON rn.oid = r.relnamespace
+		WHERE c.contype = 'f' AND n.nspname = $1 AND t.relname = $2
+		ORDER BY c.conname;`, schema, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var fk ForeignKey
+		var columns, refColumns, onUpdate, onDelete string
+		if err := rows.Scan(&fk.Name, &columns, &fk.RefTable, &refColumns, &onUpdate, &onDelete); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+
+		fk.Columns = splitColumns(columns)
+		fk.RefColumns = splitColumns(refColumns)
+		fk.OnUpdate = postgresFKActions[onUpdate]
+		fk.OnDelete = postgresFKActions[onDelete]
+		foreignKeys = append(foreignKeys, fk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over foreign keys: %w", err)
+	}
+
+	return foreignKeys, nil
+}
+
+// Fetch views from every user schema. Views outside of public carry their schema.
+func (db *Postgres) GetViews(ctx context.Context) ([]View, error) {
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT CASE WHEN table_schema = 'public' THEN '' ELSE table_schema END, table_name,
+			COALESCE(view_definition, '')
+		FROM information_schema.views
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+		ORDER BY table_schema, table_name;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch views: %w", err)
+	}
+	defer rows.Close()
+
+	var views []View
+	for rows.Next() {
+		var view View
+		if err := rows.Scan(&view.Schema, &view.Name, &view.Definition); err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+		views = append(views, view)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over views: %w", err)
+	}
+
+	return views, nil
+}
+
+// Fetch user defined triggers, skipping the internal ones backing constraints
+func (db *Postgres) GetTriggers(ctx context.Context) ([]Trigger, error) {
+	rows, err := db.Connection.QueryContext(ctx, `
+		SELECT tg.tgname,
+			CASE WHEN n.nspname = 'public' THEN t.relname ELSE n.nspname || '.' || t.relname END,
+			pg_get_triggerdef(tg.oid)
+		FROM pg_trigger tg
+		JOIN pg_class t ON t.oid = tg.tgrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE NOT tg.tgisinternal
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		ORDER BY tg.tgname;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch triggers: %w", err)
+	}
+	defer rows.Close()
+
+	var triggers []Trigger
+	for rows.Next() {
+		var trigger Trigger
+		if err := rows.Scan(&trigger.Name, &trigger.Table, &trigger.Definition); err != nil {
+			return nil, fmt.Errorf("failed to scan trigger: %w", err)
+		}
+		triggers = append(triggers, trigger)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over triggers: %w", err)
+	}
+
+	return triggers, nil
+}
@@ -0,0 +1,170 @@
+package drivers
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Optional features a driver supports
+type Capability uint
+
+const (
+	// Statements can be grouped in transactions
+	CapTransactions Capability = 1 << iota
+	// Views and triggers can be listed
+	CapViewsAndTriggers
+)
+
+// Fields the connection form asks for instead of a whole URL
+type ConnectionFields int
+
+const (
+	// Only a URL can be entered
+	URLOnly ConnectionFields = iota
+	// A path to a database file or directory
+	FileFields
+	// Host, port, user, password, database and options of a database server
+	ServerFields
+)
+
+// Creates an unconnected Database. Connect is called with the URL afterwards.
+type Factory func() Database
+
+// Driver describes a database a URL scheme connects to
+type Driver struct {
+	// Filled in by Drivers with the scheme the driver was registered under
+	Scheme       string
+	Aliases      []string
+	Name         string
+	ExampleURL   string
+	Capabilities Capability
+	// Fields the connection form builds the URL from
+	Fields ConnectionFields
+	// Port used when the URL has none, shown as a hint in the form
+	DefaultPort string
+	// URL options that open the database read-only, so it refuses writes that get past
+	// the statement check of read-only connections
+	ReadOnlyParams map[string]string
+	// Checks the shape of a URL without connecting, nil when any URL is accepted
+	ParseURL func(dbURL string) error
+	New      Factory
+}
+
+func (d Driver) Has(capability Capability) bool {
+	return d.Capabilities&capability == capability
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Driver{}
+	aliases    = map[string]string{}
+)
+
+// Makes a driver available for URLs with the given scheme. Drivers register
+// themselves from init. Registering the same scheme twice panics.
+func Register(scheme string, driver Driver) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	scheme = strings.ToLower(scheme)
+	if driver.New == nil {
+		panic("drivers: Register factory is nil for " + scheme)
+	}
+	if _, exists := registry[scheme]; exists {
+		panic("drivers: Register called twice for " + scheme)
+	}
+	if _, exists := aliases[scheme]; exists {
+		panic("drivers: Register called for alias " + scheme)
+	}
+
+	driver.Scheme = scheme
+	driver.Aliases = nil
+	registry[scheme] = driver
+}
+
+// Makes alias connect with the driver registered for scheme, e.g. postgresql for postgres
+func RegisterAlias(alias, scheme string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	alias, scheme = strings.ToLower(alias), strings.ToLower(scheme)
+	driver, exists := registry[scheme]
+	if !exists {
+		panic("drivers: RegisterAlias for unknown scheme " + scheme)
+	}
+	if _, taken := registry[alias]; taken {
+		panic("drivers: RegisterAlias called for registered scheme " + alias)
+	}
+	if _, taken := aliases[alias]; taken {
+		panic("drivers: RegisterAlias called twice for " + alias)
+	}
+
+	aliases[alias] = scheme
+	driver.Aliases = append(driver.Aliases, alias)
+	registry[scheme] = driver
+}
+
+// Returns the driver for a URL scheme or alias
+func Lookup(scheme string) (Driver, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	scheme = strings.ToLower(scheme)
+	if target, ok := aliases[scheme]; ok {
+		scheme = target
+	}
+
+	driver, ok := registry[scheme]
+	return driver, ok
+}
+
+// Returns the registered drivers sorted by scheme
+func Drivers() []Driver {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	list := make([]Driver, 0, len(registry))
+	for _, driver := range registry {
+		list = append(list, driver)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Scheme < list[j].Scheme
+	})
+
+	return list
+}
+
+// Returns the driver a database was created by
+func DriverOf(db Database) (Driver, bool) {
+	if db == nil {
+		return Driver{}, false
+	}
+
+	for _, driver := range Drivers() {
+		if reflect.TypeOf(driver.New()) == reflect.TypeOf(db) {
+			return driver, true
+		}
+	}
+
+	return Driver{}, false
+}
+
+// Whether the driver of a database has the capability. Databases of no registered
+// driver, e.g. test doubles, are taken to have every capability.
+func Supports(db Database, capability Capability) bool {
+	driver, ok := DriverOf(db)
+	return !ok || driver.Has(capability)
+}
+
+func unsupportedSchemeError(scheme string) error {
+	var schemes []string
+	for _, driver := range Drivers() {
+		schemes = append(schemes, driver.Scheme)
+	}
+
+	return fmt.Errorf("Unsupported database scheme: %q (supported: %s)", scheme, strings.Join(schemes, ", "))
+}
@@ -0,0 +1,195 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Column metadata reported by the driver
+type ColumnInfo struct {
+	Name string
+	// Declared type such as INTEGER or VARCHAR, empty when the driver does not know it
+	DatabaseType string
+	Nullable     bool
+	// Not every driver reports nullability
+	NullableKnown bool
+	// Go type the driver scans values of the column into
+	ScanType string
+}
+
+type CellKind int
+
+const (
+	NullCell CellKind = iota
+	TextCell
+	NumberCell
+	BoolCell
+	BytesCell
+	TimeCell
+)
+
+// A typed result value. Value holds the scanned Go value, decimals are kept as strings
+// so no precision is lost.
+type Cell struct {
+	Kind  CellKind
+	Value interface{}
+	// Set on time cells of DATE columns, which are shown without a time of day
+	DateOnly bool
+}
+
+// Formats the cell the way it is shown in QueryResultMsg.Rows
+func (c Cell) String() string {
+	switch v := c.Value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case []byte:
+		return "0x" + hex.EncodeToString(v)
+	case time.Time:
+		return formatTime(v, c.DateOnly)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func scanColumnInfo(rowsResult *sql.Rows, columns []string) []ColumnInfo {
+	columnInfo := make([]ColumnInfo, len(columns))
+	for i, name := range columns {
+		columnInfo[i].Name = name
+	}
+
+	// Column types are optional, fall back to names only
+	columnTypes, err := rowsResult.ColumnTypes()
+	if err != nil || len(columnTypes) != len(columns) {
+		return columnInfo
+	}
+
+	for i, columnType := range columnTypes {
+		columnInfo[i].DatabaseType = strings.ToUpper(columnType.DatabaseTypeName())
+		columnInfo[i].Nullable, columnInfo[i].NullableKnown = columnType.Nullable()
+		if scanType := columnType.ScanType(); scanType != nil {
+			columnInfo[i].ScanType = scanType.String()
+		}
+	}
+
+	return columnInfo
+}
+
+// Converts a scanned value into a typed cell, using the declared column type for raw bytes
+func newCell(value interface{}, databaseType string) Cell {
+	switch v := value.(type) {
+	case nil:
+		return Cell{Kind: NullCell}
+	case int64, int32, int16, int8, int, uint64, uint32, uint16, uint8, float64, float32:
+		return Cell{Kind: NumberCell, Value: v}
+	case bool:
+		return Cell{Kind: BoolCell, Value: v}
+	case time.Time:
+		return Cell{Kind: TimeCell, Value: v, DateOnly: databaseType == "DATE"}
+	case string:
+		return Cell{Kind: TextCell, Value: v}
+	case []byte:
+		// Copy since drivers may reuse the buffer for the next row
+		b := append([]byte(nil), v...)
+
+		switch {
+		case databaseType == "UUID" && len(b) == 16:
+			// duckdb returns uuids as their raw bytes
+			return Cell{Kind: TextCell, Value: formatUUID(b)}
+		case isBinaryType(databaseType):
+			return Cell{Kind: BytesCell, Value: b}
+		case isNumericType(databaseType):
+			// Numeric and decimal columns come back as raw bytes from most drivers
+			return Cell{Kind: NumberCell, Value: string(b)}
+		case isPrintable(b):
+			return Cell{Kind: TextCell, Value: string(b)}
+		default:
+			return Cell{Kind: BytesCell, Value: b}
+		}
+	default:
+		if cell, ok := duckDBCell(v); ok {
+			return cell
+		}
+		return Cell{Kind: TextCell, Value: fmt.Sprintf("%v", v)}
+	}
+}
+
+func isBinaryType(databaseType string) bool {
+	return strings.Contains(databaseType, "BLOB") ||
+		strings.Contains(databaseType, "BINARY") ||
+		databaseType == "BYTEA"
+}
+
+var numericTypes = map[string]bool{
+	"INT": true, "INTEGER": true, "TINYINT": true, "SMALLINT": true, "MEDIUMINT": true, "BIGINT": true,
+	"INT2": true, "INT4": true, "INT8": true, "DECIMAL": true, "NUMERIC": true, "REAL": true,
+	"FLOAT": true, "FLOAT4": true, "FLOAT8": true, "DOUBLE": true, "MONEY": true,
+}
+
+// Matches declared types such as DECIMAL(10,2) and UNSIGNED BIGINT
+func isNumericType(databaseType string) bool {
+	databaseType = strings.TrimPrefix(databaseType, "UNSIGNED ")
+	if i := strings.IndexAny(databaseType, "( "); i >= 0 {
+		databaseType = databaseType[:i]
+	}
+
+	return numericTypes[databaseType]
+}
+
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Dates are shown without a time of day and UTC times without a zone
+func formatTime(t time.Time, dateOnly bool) string {
+	if dateOnly {
+		return t.Format("2006-01-02")
+	}
+
+	if _, offset := t.Zone(); offset == 0 {
+		return t.Format("2006-01-02 15:04:05.999999999")
+	}
+
+	return t.Format("2006-01-02 15:04:05.999999999 -07:00")
+}
+
+// Wraps a failed query, reporting cancellation instead of the driver's own error
+func queryError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return contextError(ctx)
+	}
+	return fmt.Errorf("failed to execute query: %w", err)
+}
+
+func contextError(ctx context.Context) error {
+	if errors.Is(context.Cause(ctx), ErrStatementTimeout) {
+		return ErrStatementTimeout
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ErrQueryCancelled
+	}
+	return fmt.Errorf("query stopped: %w", ctx.Err())
+}
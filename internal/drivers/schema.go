@@ -0,0 +1,126 @@
+package drivers
+
+import (
+	"sort"
+	"strings"
+)
+
+// Structured schema information returned by the introspection methods of Database
+
+type Column struct {
+	Name       string
+	Type       string
+	Nullable   bool
+	Default    string
+	HasDefault bool
+	// Position of the column in the primary key, 0 when it is not part of it
+	PrimaryKey int
+}
+
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+	Primary bool
+}
+
+type ForeignKey struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnUpdate   string
+	OnDelete   string
+}
+
+// A view and its schema, like the tables of GetTables
+type View struct {
+	TableName
+	Definition string
+}
+
+type Trigger struct {
+	Name       string
+	Table      string
+	Definition string
+}
+
+// Returns the primary key columns in key order
+func PrimaryKey(columns []Column) []string {
+	var keyColumns []Column
+	for _, column := range columns {
+		if column.PrimaryKey > 0 {
+			keyColumns = append(keyColumns, column)
+		}
+	}
+
+	sort.Slice(keyColumns, func(i, j int) bool {
+		return keyColumns[i].PrimaryKey < keyColumns[j].PrimaryKey
+	})
+
+	names := make([]string, len(keyColumns))
+	for i, column := range keyColumns {
+		names[i] = column.Name
+	}
+
+	return names
+}
+
+// Implemented by databases that list tables of several schemas. Tables of the default
+// schema are listed without one.
+type SchemaNamer interface {
+	DefaultSchema() string
+}
+
+// Qualifies a name with its schema unless it is in the default schema, e.g. archive.orders
+func qualifyName(schema, name, defaultSchema string) string {
+	if schema == defaultSchema {
+		return name
+	}
+
+	return schema + "." + name
+}
+
+// A table listed by GetTables. Schema is empty for tables of the default schema, so
+// names holding dots such as "v1.orders" stay apart from the schema.
+type TableName struct {
+	Schema string
+	Name   string
+}
+
+// Names a table of a schema, leaving the schema out when it is the default one
+func newTableName(schema, name, defaultSchema string) TableName {
+	if schema == defaultSchema {
+		schema = ""
+	}
+
+	return TableName{Schema: schema, Name: name}
+}
+
+// The name qualified with its schema unless it is in the default schema, e.g. archive.orders
+func (t TableName) String() string {
+	return qualifyName(t.Schema, t.Name, "")
+}
+
+// The schema of the table, defaultSchema when it has none
+func (t TableName) schemaOr(defaultSchema string) string {
+	if t.Schema == "" {
+		return defaultSchema
+	}
+
+	return t.Schema
+}
+
+// Quotes an identifier with double quotes, as used by sqlite and postgres
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Splits a comma separated column list returned by the catalog queries
+func splitColumns(columns string) []string {
+	if columns == "" {
+		return nil
+	}
+
+	return strings.Split(columns, ",")
+}
@@ -0,0 +1,226 @@
+package drivers
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// Results of running a script statement by statement
+type ScriptResultMsg struct {
+	Results []QueryResultMsg
+	// Number of statements in the script, more than len(Results) when stopped on an error
+	Total int
+}
+
+// Splits a script into statements on semicolons. Semicolons inside quoted strings,
+// comments, dollar quoted bodies and BEGIN...END blocks of triggers, functions and
+// procedures do not end a statement. Strings are read with the escapes of the dialect.
+// Each statement keeps its terminating semicolon.
+func SplitStatements(script string, dialect Dialect) []string {
+	var statements []string
+
+	runes := []rune(script)
+	start := 0
+	depth := 0 // Nesting of BEGIN/CASE...END inside a block statement
+	var words []string
+
+	flush := func(end int) {
+		statement := strings.TrimSpace(string(runes[start:end]))
+		if len(statementWords(statement, dialect)) > 0 {
+			statements = append(statements, statement)
+		}
+		start = end
+		depth = 0
+		words = nil
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			// Line comment
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// Block comment
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+
+		case r == '\'' || r == '"' || r == '`':
+			// Quoted strings and identifiers
+			i = closingQuote(runes, i, dialect)
+
+		case r == '[':
+			// SQLite bracket quoted identifiers
+			for i < len(runes) && runes[i] != ']' {
+				i++
+			}
+
+		case r == '$':
+			// Postgres dollar quoted bodies such as $$ ... $$ or $body$ ... $body$
+			if tag, ok := dollarQuoteTag(runes[i:]); ok {
+				for i += len(tag); i < len(runes); i++ {
+					if hasRunePrefix(runes[i:], tag) {
+						i += len(tag) - 1
+						break
+					}
+				}
+			}
+
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			word := strings.ToUpper(string(runes[i:j]))
+			words = append(words, word)
+			i = j - 1
+
+			if isBlockStatement(words) {
+				switch word {
+				case "BEGIN", "CASE":
+					depth++
+				case "END":
+					// END IF, END LOOP, END WHILE and END REPEAT close constructs that were not counted
+					if next := nextWord(runes[j:]); next != "IF" && next != "LOOP" && next != "WHILE" && next != "REPEAT" && depth > 0 {
+						depth--
+					}
+				}
+			}
+
+		case r == ';' && depth == 0:
+			flush(i + 1)
+		}
+	}
+	flush(len(runes))
+
+	return statements
+}
+
+// Trigger, function and procedure bodies can contain semicolons between BEGIN and END
+func isBlockStatement(words []string) bool {
+	if len(words) < 2 || words[0] != "CREATE" {
+		return false
+	}
+
+	for i, word := range words {
+		if i > 6 {
+			break
+		}
+		if word == "TRIGGER" || word == "FUNCTION" || word == "PROCEDURE" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the opening tag of a dollar quote, e.g. $$ or $body$
+func dollarQuoteTag(runes []rune) ([]rune, bool) {
+	for i := 1; i < len(runes); i++ {
+		r := runes[i]
+		if r == '$' {
+			return runes[:i+1], true
+		}
+		// $1 style placeholders are not dollar quotes
+		if !(unicode.IsLetter(r) || r == '_' || (i > 1 && unicode.IsDigit(r))) {
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+func hasRunePrefix(runes, prefix []rune) bool {
+	if len(runes) < len(prefix) {
+		return false
+	}
+
+	for i := range prefix {
+		if runes[i] != prefix[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func nextWord(runes []rune) string {
+	i := 0
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+
+	j := i
+	for j < len(runes) && (unicode.IsLetter(runes[j]) || runes[j] == '_') {
+		j++
+	}
+
+	return strings.ToUpper(string(runes[i:j]))
+}
+
+// Runs statements in order. With stopOnError the script ends at the first failing
+// statement, otherwise every statement runs. Cancelling the context always stops the script.
+func ExecuteScript(ctx context.Context, db Database, statements []string, stopOnError bool) ScriptResultMsg {
+	return ExecuteScriptWithArgs(ctx, db, statements, nil, stopOnError)
+}
+
+// Runs a script whose statements contain placeholders. Args are keyed as returned by
+// Placeholders for the whole script, so anonymous placeholders are numbered across statements.
+func ExecuteScriptWithArgs(ctx context.Context, db Database, statements []string, args map[string]any, stopOnError bool) ScriptResultMsg {
+	script := ScriptResultMsg{Total: len(statements)}
+	dialect := DialectOf(db)
+	anonymous := 0
+
+	for _, statement := range statements {
+		var result QueryResultMsg
+		if args == nil {
+			result = db.ExecuteQuery(ctx, statement)
+		} else {
+			var statementArgs map[string]any
+			statementArgs, anonymous = scriptStatementArgs(statement, args, dialect, anonymous)
+			result = db.ExecuteQueryWithArgs(ctx, statement, statementArgs)
+		}
+		result.Query = statement
+
+		// Scripts only keep the first page of each result
+		if result.Cursor != nil {
+			result.Cursor.Close()
+			result.Cursor = nil
+		}
+
+		script.Results = append(script.Results, result)
+
+		if ctx.Err() != nil || (result.Error != nil && stopOnError) {
+			break
+		}
+	}
+
+	return script
+}
+
+// Picks the args of one statement, renumbering anonymous placeholders from the
+// script wide numbering to the statement's own
+func scriptStatementArgs(statement string, args map[string]any, dialect Dialect, anonymous int) (map[string]any, int) {
+	global := scanPlaceholders(statement, dialect, anonymous)
+	local := scanPlaceholders(statement, dialect, 0)
+
+	statementArgs := map[string]any{}
+	for i, p := range local {
+		if value, ok := args[global[i].key]; ok {
+			statementArgs[p.key] = value
+		}
+		if p.anonymous {
+			anonymous++
+		}
+	}
+
+	return statementArgs, anonymous
+}
@@ -0,0 +1,151 @@
+package drivers
+
+import (
+	"context"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type ConnectionState int
+
+const (
+	Disconnected ConnectionState = iota
+	Connecting
+	Connected
+	ConnectionFailed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	case ConnectionFailed:
+		return "failed"
+	default:
+		return "disconnected"
+	}
+}
+
+// Owns the one open connection of the selected profile. The tree and the editor share
+// it, and it is closed when another profile is selected or the app quits.
+type SessionManager struct {
+	mu      sync.Mutex
+	id      int
+	name    string
+	db      Database
+	state   ConnectionState
+	cancel  context.CancelFunc
+	closing bool
+}
+
+func NewSessionManager() *SessionManager {
+	return &SessionManager{}
+}
+
+// Closes the current connection and starts a new session for the named profile.
+// The returned id is passed to Connect.
+func (s *SessionManager) Open(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeLocked()
+	s.id++
+	s.name = name
+	s.state = Connecting
+	s.closing = false
+
+	return s.id
+}
+
+// Connects the session started by Open with the limits of its profile. Meant to run in the
+// background. If another session was opened in the meantime the new connection is closed
+// and current is false.
+func (s *SessionManager) Connect(id int, url string, limits Limits) (db Database, msg tea.Msg, current bool) {
+	s.mu.Lock()
+	if id != s.id {
+		s.mu.Unlock()
+		return nil, nil, false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	db, msg = ConnectToDatabase(ctx, url, limits)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cancel()
+
+	if id != s.id || s.closing {
+		if db != nil {
+			db.CloseConnection()
+		}
+		return nil, msg, false
+	}
+
+	s.cancel = nil
+	if db == nil {
+		s.state = ConnectionFailed
+		return nil, msg, true
+	}
+
+	s.db = db
+	s.state = Connected
+
+	return db, msg, true
+}
+
+// Closes the open connection, e.g. when the app quits
+func (s *SessionManager) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closing = true
+	return s.closeLocked()
+}
+
+func (s *SessionManager) closeLocked() error {
+	// Abandon a connect that is still in progress
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+
+	var err error
+	if s.db != nil {
+		err = s.db.CloseConnection()
+		s.db = nil
+	}
+	s.state = Disconnected
+
+	return err
+}
+
+// Id of the latest session
+func (s *SessionManager) ID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+func (s *SessionManager) Database() Database {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db
+}
+
+// Name of the profile of the latest session
+func (s *SessionManager) Name() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.name
+}
+
+func (s *SessionManager) State() ConnectionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
@@ -0,0 +1,266 @@
+package drivers
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// A database file attached to a connection under its own schema name
+type Attachment struct {
+	Schema string
+	Path   string
+}
+
+// Implemented by databases that can attach other database files, e.g. sqlite's ATTACH.
+// Tables of an attached database are listed by GetTables with its schema.
+type Attacher interface {
+	Attach(ctx context.Context, path, schema string) error
+	Detach(ctx context.Context, schema string) error
+	Attachments() []Attachment
+	// Reports attachments that were dropped since the last call because a connection
+	// failed to attach them, e.g. after their file was deleted. Nil when there are none.
+	DroppedAttachments() error
+}
+
+// ATTACH only applies to the connection it runs on, so the list is kept here and each
+// pooled connection catches up with it before it is used
+type sqliteAttachments struct {
+	mu   sync.Mutex
+	list []Attachment
+	// Bumped on every change so connections that are up to date skip the check
+	generation int
+	// Why attachments were dropped, until DroppedAttachments reports them
+	dropped []error
+}
+
+func (a *sqliteAttachments) snapshot() ([]Attachment, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.list), a.generation
+}
+
+// Removes an attachment that a connection failed to attach, so other connections
+// don't fail on it too
+func (a *sqliteAttachments) drop(attachment Attachment, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	i := slices.Index(a.list, attachment)
+	if i < 0 {
+		return
+	}
+
+	a.list = slices.Delete(a.list, i, i+1)
+	a.generation++
+	a.dropped = append(a.dropped, fmt.Errorf("Detached %s, it failed to attach: %w", attachment.Schema, err))
+}
+
+// Opens pooled connections through go-sqlite3 and brings them up to date with the attachments
+type sqliteConnector struct {
+	dsn         string
+	driver      *sqlite3.SQLiteDriver
+	attachments *sqliteAttachments
+}
+
+func (c *sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &sqliteConn{SQLiteConn: conn.(*sqlite3.SQLiteConn), attachments: c.attachments, generation: -1}
+	if err := sc.ResetSession(ctx); err != nil {
+		sc.Close()
+		return nil, err
+	}
+
+	return sc, nil
+}
+
+func (c *sqliteConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type sqliteConn struct {
+	*sqlite3.SQLiteConn
+	attachments *sqliteAttachments
+	generation  int
+}
+
+// Called by database/sql before a pooled connection is reused. Attaches and detaches
+// databases until the connection matches the attachment list.
+//
+// database/sql drops errors other than driver.ErrBadConn, which discards the connection.
+// An attachment that fails to attach is dropped instead, so queries keep working and
+// DroppedAttachments tells the user why.
+func (c *sqliteConn) ResetSession(ctx context.Context) error {
+	list, generation := c.attachments.snapshot()
+	if generation == c.generation {
+		return nil
+	}
+
+	attached, err := c.attachedSchemas(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: failed to list attached databases: %w", driver.ErrBadConn, err)
+	}
+
+	for _, attachment := range list {
+		if attached[attachment.Schema] {
+			delete(attached, attachment.Schema)
+			continue
+		}
+		if err := c.exec(ctx, "ATTACH DATABASE ? AS ?;", attachment.Path, attachment.Schema); err != nil {
+			c.attachments.drop(attachment, err)
+			// Checked again on next use, the list changed
+			generation = -1
+		}
+	}
+
+	for schema := range attached {
+		if err := c.exec(ctx, "DETACH DATABASE ?;", schema); err != nil {
+			return fmt.Errorf("%w: failed to detach %s: %w", driver.ErrBadConn, schema, err)
+		}
+	}
+
+	c.generation = generation
+	return nil
+}
+
+// Schemas attached to this connection besides main and temp
+func (c *sqliteConn) attachedSchemas(ctx context.Context) (map[string]bool, error) {
+	rows, err := c.QueryContext(ctx, "PRAGMA database_list;", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schemas := map[string]bool{}
+	values := make([]driver.Value, len(rows.Columns()))
+	for {
+		if err := rows.Next(values); err != nil {
+			break
+		}
+		if name, ok := values[1].(string); ok && name != "main" && name != "temp" {
+			schemas[name] = true
+		}
+	}
+
+	return schemas, nil
+}
+
+func (c *sqliteConn) exec(ctx context.Context, query string, args ...string) error {
+	namedArgs := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		namedArgs[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+
+	_, err := c.ExecContext(ctx, query, namedArgs)
+	return err
+}
+
+// Attaches a database file under the given schema name, by default the file name
+// without its extension. The file must exist.
+func (db *SQLite) Attach(ctx context.Context, path, schema string) error {
+	if db.Connection == nil {
+		return errors.New("no database connection")
+	}
+	if db.tx.open() {
+		return errors.New("Commit or roll back the open transaction before attaching a database")
+	}
+
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return errors.New("The database file path cannot be empty.")
+	}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("The database file %s does not exist.", path)
+		}
+		return err
+	}
+
+	schema = strings.TrimSpace(schema)
+	if schema == "" {
+		schema = tableNameForFile(path)
+	}
+	if strings.EqualFold(schema, "main") || strings.EqualFold(schema, "temp") {
+		return fmt.Errorf("%s is reserved, choose another schema name", schema)
+	}
+
+	if db.attached(schema) {
+		return fmt.Errorf("A database is already attached as %s", schema)
+	}
+
+	// Attach on one connection first so a file that isn't a database is reported here.
+	// The other connections attach it when they are next used.
+	conn, err := db.Connection.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS ?;", path, schema); err != nil {
+		return fmt.Errorf("Failed to attach %s: %w", path, err)
+	}
+
+	var count int
+	if err := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT count(*) FROM %s.sqlite_master;", quoteIdentifier(schema))).Scan(&count); err != nil {
+		conn.ExecContext(ctx, "DETACH DATABASE ?;", schema)
+		return fmt.Errorf("Failed to attach %s: %w", path, err)
+	}
+
+	db.attachments.mu.Lock()
+	defer db.attachments.mu.Unlock()
+
+	db.attachments.list = append(db.attachments.list, Attachment{Schema: schema, Path: path})
+	db.attachments.generation++
+
+	return nil
+}
+
+func (db *SQLite) attached(schema string) bool {
+	list, _ := db.attachments.snapshot()
+	return slices.ContainsFunc(list, func(a Attachment) bool { return strings.EqualFold(a.Schema, schema) })
+}
+
+// Detaches a database attached with Attach
+func (db *SQLite) Detach(ctx context.Context, schema string) error {
+	if db.tx.open() {
+		return errors.New("Commit or roll back the open transaction before detaching a database")
+	}
+
+	db.attachments.mu.Lock()
+	defer db.attachments.mu.Unlock()
+
+	i := slices.IndexFunc(db.attachments.list, func(a Attachment) bool { return a.Schema == schema })
+	if i < 0 {
+		return fmt.Errorf("No database is attached as %s", schema)
+	}
+
+	db.attachments.list = slices.Delete(db.attachments.list, i, i+1)
+	db.attachments.generation++
+
+	return nil
+}
+
+func (db *SQLite) Attachments() []Attachment {
+	list, _ := db.attachments.snapshot()
+	return list
+}
+
+func (db *SQLite) DroppedAttachments() error {
+	db.attachments.mu.Lock()
+	defer db.attachments.mu.Unlock()
+
+	err := errors.Join(db.attachments.dropped...)
+	db.attachments.dropped = nil
+	return err
+}
@@ -0,0 +1,187 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Runs a schema pragma such as table_info on a table, which may be in the schema of an
+// attached database
+func (db *SQLite) pragma(ctx context.Context, pragma string, table TableName) (*sql.Rows, error) {
+	return db.Connection.QueryContext(ctx, fmt.Sprintf("PRAGMA %s.%s(%s);", quoteIdentifier(table.schemaOr("main")), pragma, quoteIdentifier(table.Name)))
+}
+
+// Fetch column details from PRAGMA table_info
+func (db *SQLite) GetColumns(ctx context.Context, table TableName) ([]Column, error) {
+	rows, err := db.pragma(ctx, "table_info", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+
+		columns = append(columns, Column{
+			Name:       name,
+			Type:       columnType,
+			Nullable:   notNull == 0,
+			Default:    defaultValue.String,
+			HasDefault: defaultValue.Valid,
+			PrimaryKey: pk,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// Fetch indexes from PRAGMA index_list and their columns from PRAGMA index_info
+func (db *SQLite) GetIndexes(ctx context.Context, table TableName) ([]Index, error) {
+	rows, err := db.pragma(ctx, "index_list", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch indexes: %w", err)
+	}
+
+	var indexes []Index
+	for rows.Next() {
+		var seq, unique, partial int
+		var name, origin string
+		if err := rows.Scan(&seq, &name, &unique, &origin, &partial); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+
+		indexes = append(indexes, Index{Name: name, Unique: unique == 1, Primary: origin == "pk"})
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over indexes: %w", err)
+	}
+
+	// Indexes live in the schema of their table
+	for i := range indexes {
+		columns, err := db.indexColumns(ctx, TableName{Schema: table.Schema, Name: indexes[i].Name})
+		if err != nil {
+			return nil, err
+		}
+		indexes[i].Columns = columns
+	}
+
+	return indexes, nil
+}
+
+func (db *SQLite) indexColumns(ctx context.Context, index TableName) ([]string, error) {
+	rows, err := db.pragma(ctx, "index_info", index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch index columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var seqNo, cid int
+		var name sql.NullString // NULL for expressions
+		if err := rows.Scan(&seqNo, &cid, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan index column: %w", err)
+		}
+
+		if name.Valid {
+			columns = append(columns, name.String)
+		} else {
+			columns = append(columns, "<expression>")
+		}
+	}
+
+	return columns, rows.Err()
+}
+
+// Fetch foreign keys from PRAGMA foreign_key_list, one entry per constraint
+func (db *SQLite) GetForeignKeys(ctx context.Context, table TableName) ([]ForeignKey, error) {
+	rows, err := db.pragma(ctx, "foreign_key_list", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	lastID := -1
+	for rows.Next() {
+		var id, seq int
+		var refTable, from, onUpdate, onDelete, match string
+		var to sql.NullString // NULL when referencing the primary key implicitly
+		if err := rows.Scan(&id, &seq, &refTable, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+
+		// Columns of a composite key share the same id
+		if id != lastID {
+			foreignKeys = append(foreignKeys, ForeignKey{
+				Name:     fmt.Sprintf("fk_%s_%d", table, id),
+				RefTable: refTable,
+				OnUpdate: onUpdate,
+				OnDelete: onDelete,
+			})
+			lastID = id
+		}
+
+		fk := &foreignKeys[len(foreignKeys)-1]
+		fk.Columns = append(fk.Columns, from)
+		fk.RefColumns = append(fk.RefColumns, to.String)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over foreign keys: %w", err)
+	}
+
+	return foreignKeys, nil
+}
+
+// Views of attached databases carry their schema like their tables
+func (db *SQLite) GetViews(ctx context.Context) ([]View, error) {
+	var views []View
+	err := db.eachSchema(ctx, "SELECT name, sql FROM %s.sqlite_master WHERE type='view' ORDER BY name;", func(schema string, rows *sql.Rows) error {
+		var view View
+		if err := rows.Scan(&view.Name, &view.Definition); err != nil {
+			return fmt.Errorf("failed to scan view: %w", err)
+		}
+		view.Schema = newTableName(schema, view.Name, "main").Schema
+		views = append(views, view)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch views: %w", err)
+	}
+
+	return views, nil
+}
+
+func (db *SQLite) GetTriggers(ctx context.Context) ([]Trigger, error) {
+	var triggers []Trigger
+	err := db.eachSchema(ctx, "SELECT name, tbl_name, sql FROM %s.sqlite_master WHERE type='trigger' ORDER BY name;", func(schema string, rows *sql.Rows) error {
+		var trigger Trigger
+		if err := rows.Scan(&trigger.Name, &trigger.Table, &trigger.Definition); err != nil {
+			return fmt.Errorf("failed to scan trigger: %w", err)
+		}
+		trigger.Name = qualifyName(schema, trigger.Name, "main")
+		trigger.Table = qualifyName(schema, trigger.Table, "main")
+		triggers = append(triggers, trigger)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch triggers: %w", err)
+	}
+
+	return triggers, nil
+}
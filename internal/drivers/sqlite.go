@@ -1,14 +1,16 @@
 package drivers
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 
 	"github.com/jdkingsbury/americano/msgtypes"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 // TODO: Check to see how we plan to display or log different errors and notifications
@@ -16,43 +18,91 @@ import (
 type SQLite struct {
 	Connection    *sql.DB
 	connectionUrl string
+	cursor        cursorHolder
+	tx            transaction
+	limits        Limits
+	attachments   sqliteAttachments
 }
 
-// Parses out sqlite:/// from the url
-func normalizeSQLiteURL(url string) (string, error) {
-	if len(url) >= 10 && url[:10] == "sqlite:///" {
-		return url[10:], nil
-	}
-	// return "", msgtypes.NewErrMsg(err, "Invalid SQLite URL format")
-	return "", errors.New("Invalid SQLite URL format")
+func init() {
+	Register("sqlite", Driver{
+		Name:         "SQLite",
+		ExampleURL:   "sqlite://./dev.db?create=true",
+		Capabilities: CapTransactions | CapViewsAndTriggers,
+		Fields:       FileFields,
+		// query_only also covers attached databases and in-memory databases
+		ReadOnlyParams: map[string]string{"mode": "ro", "_query_only": "true"},
+		ParseURL: func(dbURL string) error {
+			_, err := ParseSQLiteURL(dbURL)
+			return err
+		},
+		New: func() Database { return &SQLite{} },
+	})
 }
 
-// Opens a connection to sqlite database
-func (db *SQLite) Connect(url string) error {
-	normalizedURL, err := normalizeSQLiteURL(url)
-	if err != nil {
-		return err
-	}
+// Parsed form of a sqlite URL, see FileURL for the accepted forms. The params are
+// passed through to go-sqlite3, e.g. mode=ro, _foreign_keys=on or _journal_mode=WAL.
+type SQLiteConfig struct {
+	FileURL
+}
+
+// Counts in-memory databases so each connection gets its own
+var memoryDatabases atomic.Int64
+
+func ParseSQLiteURL(dbURL string) (SQLiteConfig, error) {
+	fileURL, err := parseFileURL(dbURL, "sqlite", "SQLite")
+	return SQLiteConfig{fileURL}, err
+}
+
+// go-sqlite3 data source name for the config
+func (c SQLiteConfig) DSN() string {
+	params := c.params()
 
-	if normalizedURL == "" {
-		return errors.New("The database file path cannot be empty.")
+	// Cleaned so sqlite:////abs/path doesn't read as a URI authority
+	path := filepath.Clean(c.Path)
+
+	// A named shared cache lets every pooled connection see the same in-memory database
+	if c.Memory {
+		path = fmt.Sprintf("americano-memory-%d", memoryDatabases.Add(1))
+		params.Set("mode", "memory")
+		params.Set("cache", "shared")
 	}
 
-	if _, err := os.Stat(normalizedURL); os.IsNotExist(err) {
-		return fmt.Errorf("The database file %s does not exist.", url)
+	dsn := "file:" + strings.ReplaceAll(strings.ReplaceAll(path, "?", "%3f"), "#", "%23")
+	if len(params) > 0 {
+		dsn += "?" + params.Encode()
 	}
 
-	conn, err := sql.Open("sqlite3", normalizedURL)
+	return dsn
+}
+
+// Opens a connection to sqlite database
+func (db *SQLite) Connect(ctx context.Context, url string) error {
+	config, err := ParseSQLiteURL(url)
 	if err != nil {
 		return err
 	}
 
+	if err := config.checkExists(); err != nil {
+		return err
+	}
+
+	// Every connection of the pool attaches the databases attached with Attach
+	conn := sql.OpenDB(&sqliteConnector{
+		dsn:         config.DSN(),
+		driver:      &sqlite3.SQLiteDriver{},
+		attachments: &db.attachments,
+	})
+
 	// Assign the connection to the SQLite struct
 	db.Connection = conn
-	db.connectionUrl = normalizedURL
+	db.connectionUrl = config.Path
+	if config.Memory {
+		db.connectionUrl = ":memory:"
+	}
 
 	// Test Connection
-	if err := db.Connection.Ping(); err != nil {
+	if err := db.Connection.PingContext(ctx); err != nil {
 		db.Connection.Close()
 		return msgtypes.NewErrMsg(fmt.Errorf("Failed to ping the database: %w", err))
 	} else {
@@ -62,60 +112,76 @@ func (db *SQLite) Connect(url string) error {
 	return nil
 }
 
-// Execute db query
-func (db *SQLite) ExecuteQuery(query string) QueryResultMsg {
-	var columns []string
-	var rows [][]string
+// Execute db query. Large results keep a cursor open for the result pane to page through.
+func (db *SQLite) ExecuteQuery(ctx context.Context, query string) QueryResultMsg {
+	return runQuery(ctx, db.tx.queryer(db.Connection), query, db.Dialect(), db.limits, &db.cursor)
+}
 
-	// Execute the query
-	rowsResult, err := db.Connection.Query(query)
-	if err != nil {
-		return QueryResultMsg{Error: fmt.Errorf("failed to execute query: %w", err)}
-	}
-	defer rowsResult.Close()
+// Uses the default dialect
+func (db *SQLite) Dialect() Dialect {
+	return Dialect{}
+}
+
+// Execute db query with bind args for its ?, :name or $1 placeholders
+func (db *SQLite) ExecuteQueryWithArgs(ctx context.Context, query string, args map[string]any) QueryResultMsg {
+	return runQueryWithArgs(ctx, db.tx.queryer(db.Connection), query, args, db.Dialect(), db.limits, &db.cursor)
+}
 
-	// Get column names
-	columns, err = rowsResult.Columns()
+// Shows the steps of EXPLAIN QUERY PLAN, flagging full table scans and temp b-trees
+func (db *SQLite) ExplainQuery(ctx context.Context, query string, args map[string]any) QueryPlanMsg {
+	ctx, _, release := withStatementTimeout(ctx, db.limits)
+	defer release()
+
+	rows, err := explainRows(ctx, db.tx.queryer(db.Connection), "EXPLAIN QUERY PLAN", query, args, db.Dialect(), &db.cursor)
 	if err != nil {
-		return QueryResultMsg{Error: fmt.Errorf("failed to get columns: %w", err)}
+		return QueryPlanMsg{Query: query, Error: err}
 	}
+	defer rows.Close()
 
-	// Process rows
-	for rowsResult.Next() {
-		// Create a slice to hold row values
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range columns {
-			valuePtrs[i] = &values[i]
-		}
-
-		// Scan row values into pointers
-		if err := rowsResult.Scan(valuePtrs...); err != nil {
-			return QueryResultMsg{Error: fmt.Errorf("failed to scan row: %w", err)}
+	var nodes []PlanNode
+	for rows.Next() {
+		var node PlanNode
+		var notUsed int
+		if err := rows.Scan(&node.ID, &node.Parent, &notUsed, &node.Detail); err != nil {
+			return QueryPlanMsg{Query: query, Error: err}
 		}
+		node.Warning = sqlitePlanWarning(node.Detail)
+		nodes = append(nodes, node)
+	}
 
-		// Convert values to strings
-		row := make([]string, len(columns))
-		for i, value := range values {
-			if value == nil {
-				row[i] = "NULL"
-			} else {
-				row[i] = fmt.Sprintf("%v", value)
-			}
-		}
-		rows = append(rows, row)
+	if err := rows.Err(); err != nil {
+		return QueryPlanMsg{Query: query, Error: queryError(ctx, err)}
 	}
 
-	// Check for errors from iterating over rows
-	if err := rowsResult.Err(); err != nil {
-		return QueryResultMsg{Error: fmt.Errorf("error iterating over rows: %w", err)}
+	return QueryPlanMsg{Query: query, Nodes: nodes}
+}
+
+// SCAN steps that don't use an index read the whole table, e.g. "SCAN users" but not
+// "SCAN users USING COVERING INDEX users_name". Older versions write "SCAN TABLE users".
+func sqlitePlanWarning(detail string) string {
+	switch {
+	case strings.Contains(detail, "USE TEMP B-TREE"):
+		return PlanTempBTree
+	case strings.HasPrefix(detail, "SCAN ") && !strings.Contains(detail, " INDEX ") && !strings.Contains(detail, "CONSTANT ROW"):
+		return PlanFullTableScan
 	}
 
-	return QueryResultMsg{Columns: columns, Rows: rows, Error: nil}
+	return ""
+}
+
+func (db *SQLite) SetLimits(limits Limits) {
+	db.limits = limits
 }
 
 // Close connection to sqlite database
 func (db *SQLite) CloseConnection() error {
+	// An open transaction is rolled back rather than left on a closed pool
+	if db.tx.open() {
+		db.tx.end(false, &db.cursor)
+	}
+
+	db.cursor.close()
+
 	if db.Connection != nil {
 		return db.Connection.Close()
 	}
@@ -123,7 +189,24 @@ func (db *SQLite) CloseConnection() error {
 	return nil
 }
 
-func (db *SQLite) GetDatabaseName() (string, error) {
+// Starts a transaction that the following statements run in until Commit or Rollback
+func (db *SQLite) BeginTransaction(ctx context.Context) error {
+	return db.tx.begin(ctx, db.Connection)
+}
+
+func (db *SQLite) Commit() error {
+	return db.tx.end(true, &db.cursor)
+}
+
+func (db *SQLite) Rollback() error {
+	return db.tx.end(false, &db.cursor)
+}
+
+func (db *SQLite) InTransaction() bool {
+	return db.tx.open()
+}
+
+func (db *SQLite) GetDatabaseName(ctx context.Context) (string, error) {
 	if db.connectionUrl == "" {
 		return "", errors.New("no database connection")
 	}
@@ -133,22 +216,73 @@ func (db *SQLite) GetDatabaseName() (string, error) {
 	return dbName, nil
 }
 
-// Fetch table names from SQLite database
-func (db *SQLite) GetTables() ([]string, error) {
-	rows, err := db.Connection.Query("SELECT name FROM sqlite_master WHERE type='table';")
+// Tables of main are listed first, followed by those of attached databases with their schema
+func (db *SQLite) GetTables(ctx context.Context) ([]TableName, error) {
+	var tables []TableName
+	err := db.eachSchema(ctx, "SELECT name FROM %s.sqlite_master WHERE type='table' ORDER BY name;", func(schema string, rows *sql.Rows) error {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, newTableName(schema, table, "main"))
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch tables: %w", err)
 	}
+
+	return tables, nil
+}
+
+func (db *SQLite) DefaultSchema() string {
+	return "main"
+}
+
+// Schema names of main and the attached databases, temp is left out
+func (db *SQLite) schemas(ctx context.Context) ([]string, error) {
+	rows, err := db.Connection.QueryContext(ctx, "SELECT name FROM pragma_database_list WHERE name != 'temp' ORDER BY seq;")
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
-	var tables []string
+	var schemas []string
 	for rows.Next() {
-		var table string
-		if err := rows.Scan(&table); err != nil {
-			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return nil, err
 		}
-		tables = append(tables, table)
+		schemas = append(schemas, schema)
 	}
 
-	return tables, nil
+	return schemas, rows.Err()
+}
+
+// Runs a sqlite_master query against every schema, %s is replaced by the quoted schema name
+func (db *SQLite) eachSchema(ctx context.Context, query string, scan func(schema string, rows *sql.Rows) error) error {
+	schemas, err := db.schemas(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, schema := range schemas {
+		rows, err := db.Connection.QueryContext(ctx, fmt.Sprintf(query, quoteIdentifier(schema)))
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			if err := scan(schema, rows); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,192 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Leading keywords of statements that produce a result set
+var rowReturningKeywords = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"VALUES":   true,
+	"PRAGMA":   true,
+	"EXPLAIN":  true,
+	"SHOW":     true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"TABLE":    true,
+}
+
+// Reports whether a statement should be run with Query rather than Exec.
+// INSERT, UPDATE and DELETE with a RETURNING clause also produce rows.
+func returnsRows(query string, dialect Dialect) bool {
+	words := statementWords(query, dialect)
+	if len(words) == 0 {
+		return false
+	}
+
+	if rowReturningKeywords[words[0]] {
+		return true
+	}
+
+	for _, word := range words[1:] {
+		if word == "RETURNING" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Leading keywords of statements a read-only connection runs
+var readKeywords = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"VALUES":   true,
+	"TABLE":    true,
+	"PRAGMA":   true,
+	"EXPLAIN":  true,
+	"SHOW":     true,
+	"DESCRIBE": true,
+	"DESC":     true,
+}
+
+// Keywords that make a read statement write, e.g. a data modifying CTE, SELECT ... INTO
+// or EXPLAIN ANALYZE DELETE. SELECT ... FOR UPDATE is refused too, it takes write locks.
+var writeKeywords = map[string]bool{
+	"INSERT":   true,
+	"UPDATE":   true,
+	"DELETE":   true,
+	"MERGE":    true,
+	"INTO":     true,
+	"CREATE":   true,
+	"DROP":     true,
+	"ALTER":    true,
+	"TRUNCATE": true,
+}
+
+// Refuses statements that may write, for read-only connections. Statements are checked
+// by their keywords, so the database is also opened read-only where the driver allows.
+func checkReadOnly(query string, dialect Dialect) error {
+	words := statementWords(query, dialect)
+	if len(words) == 0 {
+		return nil
+	}
+
+	if !readKeywords[words[0]] {
+		return fmt.Errorf("%w: %s statements are not allowed", ErrReadOnly, words[0])
+	}
+
+	for _, word := range words[1:] {
+		if writeKeywords[word] {
+			return fmt.Errorf("%w: statements with %s are not allowed", ErrReadOnly, word)
+		}
+	}
+
+	// Pragmas that set a value, e.g. PRAGMA journal_mode = WAL or PRAGMA user_version(5)
+	if words[0] == "PRAGMA" && (strings.Contains(query, "=") || !readsPragmaArgument(query, dialect)) {
+		return fmt.Errorf("%w: pragmas that set a value are not allowed", ErrReadOnly)
+	}
+
+	return nil
+}
+
+// Pragmas whose argument is what they read, e.g. PRAGMA table_info(users)
+var argumentPragmas = map[string]bool{
+	"TABLE_INFO":        true,
+	"TABLE_XINFO":       true,
+	"TABLE_LIST":        true,
+	"INDEX_LIST":        true,
+	"INDEX_INFO":        true,
+	"INDEX_XINFO":       true,
+	"FOREIGN_KEY_LIST":  true,
+	"FOREIGN_KEY_CHECK": true,
+	"INTEGRITY_CHECK":   true,
+	"QUICK_CHECK":       true,
+	"STORAGE_INFO":      true,
+	"SHOW":              true,
+}
+
+// Whether a pragma only reads, taking no argument or one it reads from. The name is the
+// last word before the argument, e.g. PRAGMA main.table_info(users).
+func readsPragmaArgument(query string, dialect Dialect) bool {
+	head, _, found := strings.Cut(query, "(")
+	if !found {
+		return true
+	}
+
+	words := statementWords(head, dialect)
+	return len(words) > 0 && argumentPragmas[words[len(words)-1]]
+}
+
+// Splits a statement into upper cased words, skipping comments and string literals
+func statementWords(query string, dialect Dialect) []string {
+	var words []string
+	var word strings.Builder
+
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, strings.ToUpper(word.String()))
+			word.Reset()
+		}
+	}
+
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			// Line comment
+			flush()
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// Block comment
+			flush()
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+
+		case r == '\'' || r == '"' || r == '`':
+			// Quoted strings and identifiers
+			flush()
+			i = closingQuote(runes, i, dialect)
+
+		case isWordRune(r):
+			word.WriteRune(r)
+
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return words
+}
+
+// Runs a statement that does not return rows and reports the rows it affected
+func execStatement(ctx context.Context, conn queryer, query string, args ...any) QueryResultMsg {
+	result, err := conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return QueryResultMsg{Error: queryError(ctx, err)}
+	}
+
+	msg := QueryResultMsg{IsExec: true}
+
+	// Not every driver supports both, e.g. postgres has no LastInsertId
+	if rowsAffected, err := result.RowsAffected(); err == nil {
+		msg.RowsAffected = rowsAffected
+	}
+	if lastInsertId, err := result.LastInsertId(); err == nil {
+		msg.LastInsertId = lastInsertId
+	}
+
+	return msg
+}
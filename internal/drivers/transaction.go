@@ -0,0 +1,84 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Returned by Commit and Rollback when there is nothing to end
+var ErrNoTransaction = errors.New("no transaction is open")
+
+// Pins a *sql.Tx for manual commit mode, so every statement runs on the same
+// pooled connection until the transaction is committed or rolled back
+type transaction struct {
+	mu sync.Mutex
+	tx *sql.Tx
+}
+
+func (t *transaction) begin(ctx context.Context, conn *sql.DB) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tx != nil {
+		return errors.New("a transaction is already open")
+	}
+
+	// database/sql rolls a transaction back when its context is done, and the
+	// transaction has to outlive the statement that started it
+	tx, err := conn.BeginTx(context.WithoutCancel(ctx), nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	t.tx = tx
+
+	return nil
+}
+
+// Statements run in the open transaction, or on the pool when there is none
+func (t *transaction) queryer(conn *sql.DB) queryer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tx != nil {
+		return t.tx
+	}
+	return conn
+}
+
+// Commits or rolls back the open transaction. An open cursor is closed first as
+// it holds the connection the transaction runs on.
+func (t *transaction) end(commit bool, openCursor *cursorHolder) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tx == nil {
+		return ErrNoTransaction
+	}
+
+	openCursor.close()
+
+	tx := t.tx
+	t.tx = nil
+
+	if commit {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	}
+
+	if err := tx.Rollback(); err != nil {
+		return fmt.Errorf("failed to roll back transaction: %w", err)
+	}
+	return nil
+}
+
+func (t *transaction) open() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.tx != nil
+}
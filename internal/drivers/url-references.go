@@ -0,0 +1,246 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/* ${VAR} and ${secret:name} references in connection URLs, expanded when connecting */
+
+var (
+	// Matches a reference, the name is the first submatch
+	referencePattern = regexp.MustCompile(`\$\{(secret:[^}]+|[A-Za-z_][A-Za-z0-9_]*)\}`)
+	secretPattern    = regexp.MustCompile(`\$\{secret:([^}]+)\}`)
+)
+
+// Returned when a URL references secrets before they were unlocked
+var ErrSecretsLocked = errors.New("secrets are locked")
+
+// Looks up the value of a secret by name
+type SecretLookup func(name string) (string, bool)
+
+var (
+	secretsMu    sync.RWMutex
+	secretLookup SecretLookup
+)
+
+// Sets where ${secret:name} references are looked up, nil while secrets are locked
+func SetSecretLookup(lookup SecretLookup) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	secretLookup = lookup
+}
+
+// Whether the URL references secrets, so they need to be unlocked before connecting
+func HasSecretReferences(dbURL string) bool {
+	return secretPattern.MatchString(dbURL)
+}
+
+// Names of the secrets a URL references
+func SecretNames(dbURL string) []string {
+	var names []string
+	for _, match := range secretPattern.FindAllStringSubmatch(dbURL, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// A reference and the value it expanded to
+type expandedReference struct {
+	reference string
+	value     string
+}
+
+// Looks up the value of a ${VAR} or ${secret:name} reference
+func lookupReference(name string, secrets SecretLookup) (string, error) {
+	secret, isSecret := strings.CutPrefix(name, "secret:")
+	if !isSecret {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("Environment variable %s is not set", name)
+		}
+		return value, nil
+	}
+
+	if secrets == nil {
+		return "", fmt.Errorf("The connection URL uses secrets, unlock them first: %w", ErrSecretsLocked)
+	}
+
+	value, ok := secrets(secret)
+	if !ok {
+		return "", fmt.Errorf("Unknown secret %q", secret)
+	}
+	return value, nil
+}
+
+// Replaces the references of a URL with the values of environment variables and
+// secrets. Returns what was expanded for redactError.
+func expandReferences(dbURL string) (string, []expandedReference, error) {
+	if !hasReferences(dbURL) {
+		return dbURL, nil, nil
+	}
+
+	secretsMu.RLock()
+	secrets := secretLookup
+	secretsMu.RUnlock()
+
+	var tokens referenceTokens
+	protected := tokens.protect(dbURL)
+
+	expanded := make([]expandedReference, len(tokens))
+	for i, reference := range tokens {
+		value, err := lookupReference(referencePattern.FindStringSubmatch(reference)[1], secrets)
+		if err != nil {
+			return "", nil, err
+		}
+		expanded[i] = expandedReference{reference: reference, value: value}
+	}
+
+	expand := func(s string) string {
+		for i, e := range expanded {
+			s = strings.ReplaceAll(s, referenceToken(i), e.value)
+		}
+		return s
+	}
+
+	if expandedURL, err := expandParts(protected, expand); err == nil {
+		return expandedURL, expanded, nil
+	}
+
+	// A reference that makes up the whole URL is replaced as is, e.g. ${DATABASE_URL}
+	return expand(protected), expanded, nil
+}
+
+func hasReferences(dbURL string) bool {
+	return referencePattern.MatchString(dbURL)
+}
+
+// Expands the tokens within each part of the URL and builds it again, which escapes
+// characters such as @ or / the way each part needs
+func expandParts(protected string, expand func(string) string) (string, error) {
+	scheme, _, _ := strings.Cut(protected, ":")
+	driver, ok := Lookup(scheme)
+	if !ok || driver.Fields == URLOnly {
+		return "", errors.New("no URL parts")
+	}
+
+	parts, err := splitURL(driver, protected)
+	if err != nil {
+		return "", err
+	}
+
+	options := parts.Options
+	parts = parts.apply(expand)
+	parts.Options, err = expandOptions(options, expand)
+	if err != nil {
+		return "", err
+	}
+
+	return buildURL(driver, parts)
+}
+
+// Expands the values of a query string and escapes them again
+func expandOptions(options string, expand func(string) string) (string, error) {
+	query, err := url.ParseQuery(options)
+	if err != nil {
+		return "", err
+	}
+
+	for key, values := range query {
+		for i := range values {
+			values[i] = expand(values[i])
+		}
+		query[key] = values
+	}
+
+	return query.Encode(), nil
+}
+
+// Puts the references back in place of expanded values in an error that may quote
+// the URL, e.g. from url.Parse, so values never show up in the TUI
+func redactError(err error, expanded []expandedReference) error {
+	if err == nil {
+		return nil
+	}
+
+	// Longer values first, so a value that holds another is replaced whole
+	expanded = slices.Clone(expanded)
+	sort.SliceStable(expanded, func(i, j int) bool {
+		return len(expanded[i].value) > len(expanded[j].value)
+	})
+
+	message := err.Error()
+	for _, e := range expanded {
+		if e.value == "" {
+			continue
+		}
+
+		// The value may be quoted as it was escaped in the URL
+		userinfo := strings.TrimPrefix(url.UserPassword("", e.value).String(), ":")
+		for _, form := range []string{e.value, url.QueryEscape(e.value), url.PathEscape(e.value), userinfo} {
+			message = strings.ReplaceAll(message, form, e.reference)
+		}
+	}
+
+	if message == err.Error() {
+		return err
+	}
+
+	// The original error still holds the values, only the sentinels it wraps are kept
+	var sentinels []error
+	for _, sentinel := range redactedSentinels {
+		if errors.Is(err, sentinel) {
+			sentinels = append(sentinels, sentinel)
+		}
+	}
+	return redactedError{message: message, sentinels: sentinels}
+}
+
+// Errors that stay matchable with errors.Is once an error is redacted
+var redactedSentinels = []error{ErrSecretsLocked, context.Canceled, context.DeadlineExceeded}
+
+type redactedError struct {
+	message   string
+	sentinels []error
+}
+
+func (e redactedError) Error() string {
+	return e.message
+}
+
+func (e redactedError) Unwrap() []error {
+	return e.sentinels
+}
+
+// Swaps references for plain tokens, so URLs holding them can be parsed and escaped
+// like any other URL. restore swaps them back.
+type referenceTokens []string
+
+func (r *referenceTokens) protect(s string) string {
+	return referencePattern.ReplaceAllStringFunc(s, func(reference string) string {
+		*r = append(*r, reference)
+		return referenceToken(len(*r) - 1)
+	})
+}
+
+func (r referenceTokens) restore(s string) string {
+	for i, reference := range r {
+		s = strings.ReplaceAll(s, referenceToken(i), reference)
+	}
+	return s
+}
+
+// Letters and digits only, so no part of a URL escapes it. The x ends it, so token 1
+// isn't found inside token 10.
+func referenceToken(i int) string {
+	return fmt.Sprintf("americanoref%dx", i)
+}
@@ -0,0 +1,131 @@
+package panes
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+/* Asks for a database file to attach to the open connection */
+
+type CancelAttachMsg struct{}
+
+type SubmitAttachMsg struct {
+	Path string
+	// Schema name for the attached database, empty to name it after the file
+	Schema string
+}
+
+// Order of the attach form inputs
+const (
+	attachPathInput = iota
+	attachSchemaInput
+	attachInputCount
+)
+
+type AttachFormModel struct {
+	focusIndex int
+	inputs     []textinput.Model
+	keys       dbFormKeyMap
+}
+
+func NewAttachFormModel() *AttachFormModel {
+	m := AttachFormModel{
+		inputs: make([]textinput.Model, attachInputCount),
+		keys:   newDBFormKeyMap(),
+	}
+
+	for i := range m.inputs {
+		ti := textinput.New()
+		ti.CharLimit = 0
+		ti.Width = 30
+
+		switch i {
+		case attachPathInput:
+			ti.Placeholder = "Database File, e.g. ./archive.db"
+			ti.Focus()
+		case attachSchemaInput:
+			ti.Placeholder = "Schema Name (optional)"
+		}
+
+		m.inputs[i] = ti
+	}
+
+	return &m
+}
+
+func (m *AttachFormModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *AttachFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.CancelForm):
+			return m, func() tea.Msg {
+				return CancelAttachMsg{}
+			}
+
+		case key.Matches(msg, m.keys.NextInput):
+			m.focusIndex = (m.focusIndex + 1) % (len(m.inputs) + 1)
+
+		case key.Matches(msg, m.keys.PrevInput):
+			m.focusIndex = (m.focusIndex - 1 + len(m.inputs) + 1) % (len(m.inputs) + 1)
+
+		case key.Matches(msg, m.keys.SubmitForm):
+			// The schema name is optional, so enter on either input submits
+			submit := SubmitAttachMsg{
+				Path:   m.inputs[attachPathInput].Value(),
+				Schema: m.inputs[attachSchemaInput].Value(),
+			}
+			return m, func() tea.Msg {
+				return submit
+			}
+		}
+
+		// Update focus for inputs
+		for i := range m.inputs {
+			if i == m.focusIndex {
+				m.inputs[i].Focus()
+			} else {
+				m.inputs[i].Blur()
+			}
+		}
+	}
+
+	// Update all inputs
+	for i := range m.inputs {
+		var cmd tea.Cmd
+		m.inputs[i], cmd = m.inputs[i].Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+func (m *AttachFormModel) View() string {
+	var output string
+
+	output += formTitleStyle.Render("Attach Database") + "\n\n"
+
+	// Input fields
+	for i := range m.inputs {
+		if i == m.focusIndex {
+			output += formFocusedStyle.Render(m.inputs[i].View()) + "\n"
+		} else {
+			output += formBlurredStyle.Render(m.inputs[i].View()) + "\n"
+		}
+	}
+
+	// Button field
+	if m.focusIndex == len(m.inputs) {
+		output += formSubmitStyle.Render("\n[ Attach ]\n")
+	} else {
+		output += formBlurredSubmit.Render("\nAttach\n")
+	}
+
+	return output
+}
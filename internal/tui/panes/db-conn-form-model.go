@@ -1,10 +1,23 @@
 package panes
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/jdkingsbury/americano/internal/drivers"
 )
 
 var (
@@ -13,28 +26,100 @@ var (
 	formBlurredStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color(subtle)).Faint(true).Padding(0, 1) // Muted for unfocused input
 	formSubmitStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color(rose)).Bold(true).Padding(0, 1)    // Rose for the submit button
 	formBlurredSubmit = lipgloss.NewStyle().Foreground(lipgloss.Color(muted)).Faint(true).Padding(0, 1)  // Muted for inactive submit button
+	formSchemeStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color(foam))                             // Foam for supported schemes
+	formHintStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color(muted))                            // Muted for example URLs
+	formErrorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color(love)).Padding(0, 1)               // Love for invalid input
 )
 
 type CancelFormMsg struct{}
 
 type SubmitFormMsg struct {
-	Name string
-	URL  string
+	Name   string
+	URL    string
+	Limits drivers.Limits
+	// Set when the connection at Index was edited rather than a new one added
+	Edit  bool
+	Index int
+	// Passwords to store encrypted before the connection is saved, by secret name.
+	// The URL references them as ${secret:name}.
+	Secrets map[string]string
+}
+
+// Order of the form inputs
+const (
+	nameInput = iota
+	urlInput
+	timeoutInput
+	maxRowsInput
+	// Inputs the URL is built from once a driver is chosen
+	pathInput
+	hostInput
+	portInput
+	userInput
+	passwordInput
+	databaseInput
+	optionsInput
+	formInputCount
+)
+
+// Buttons follow the inputs in the focus order
+const (
+	submitButton = formInputCount + iota
+	testButton
+)
+
+// Inputs that take the place of the URL input for each kind of driver
+var connectionInputs = map[drivers.ConnectionFields][]int{
+	drivers.URLOnly:      {urlInput},
+	drivers.FileFields:   {pathInput, optionsInput},
+	drivers.ServerFields: {hostInput, portInput, userInput, passwordInput, databaseInput, optionsInput},
+}
+
+// How long the Test button waits for the database to answer
+const connectionTestTimeout = 10 * time.Second
+
+// Outcome of the Test button. ID tells results of an earlier test apart.
+type ConnectionTestedMsg struct {
+	ID  int
+	Err error
 }
 
 type DBFormModel struct {
+	// Position in focusOrder
 	focusIndex int
 	inputs     []textinput.Model
 	submit     string
 	title      string
 	keys       dbFormKeyMap
+	drivers    []drivers.Driver
+	err        error
+	// Driver the URL is built for from separate inputs, nil while the URL is typed
+	driver *drivers.Driver
+	// Only statements that read data may run on the connection
+	readOnly bool
+	// The password goes to the encrypted secrets file instead of the URL
+	storeSecret bool
+	// List index of the connection being edited, -1 when adding one
+	editIndex int
+	// Problem with the typed URL, checked as it is typed
+	urlErr    error
+	urlDriver string
+	// State of the Test button
+	testID     int
+	testing    bool
+	tested     bool
+	testErr    error
+	cancelTest context.CancelFunc
 }
 
 type dbFormKeyMap struct {
-	CancelForm key.Binding
-	NextInput  key.Binding
-	PrevInput  key.Binding
-	SubmitForm key.Binding
+	CancelForm     key.Binding
+	NextInput      key.Binding
+	PrevInput      key.Binding
+	SubmitForm     key.Binding
+	ToggleReadOnly key.Binding
+	SwitchDriver   key.Binding
+	StoreSecret    key.Binding
 }
 
 func newDBFormKeyMap() dbFormKeyMap {
@@ -55,15 +140,29 @@ func newDBFormKeyMap() dbFormKeyMap {
 			key.WithKeys("enter"),
 			key.WithHelp("enter", "submit form"),
 		),
+		ToggleReadOnly: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "toggle read-only"),
+		),
+		SwitchDriver: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "switch connection type"),
+		),
+		StoreSecret: key.NewBinding(
+			key.WithKeys("ctrl+k"),
+			key.WithHelp("ctrl+k", "store password encrypted"),
+		),
 	}
 }
 
 func NewDBFormModel() *DBFormModel {
 	m := DBFormModel{
-		inputs: make([]textinput.Model, 2),
-		submit: "[ Submit ]",
-		title:  "Add Connection",
-		keys:   newDBFormKeyMap(),
+		inputs:    make([]textinput.Model, formInputCount),
+		submit:    "[ Submit ]",
+		title:     "Add Connection",
+		keys:      newDBFormKeyMap(),
+		drivers:   drivers.Drivers(),
+		editIndex: -1,
 	}
 
 	var ti textinput.Model
@@ -73,11 +172,34 @@ func NewDBFormModel() *DBFormModel {
 		ti.Width = 30
 
 		switch i {
-		case 0:
+		case nameInput:
 			ti.Placeholder = "Enter Connection Name"
 			ti.Focus()
-		case 1:
+		case urlInput:
 			ti.Placeholder = "Enter Connection URL"
+			// URLs with several options or secret references run long
+			ti.CharLimit = 0
+		case timeoutInput:
+			ti.Placeholder = "Statement Timeout, e.g. 30s (optional)"
+		case maxRowsInput:
+			ti.Placeholder = "Max Rows (optional)"
+		case pathInput:
+			ti.Placeholder = "Database Path, e.g. ./dev.db"
+		case hostInput:
+			ti.Placeholder = "Host, e.g. localhost"
+		case portInput:
+			ti.Placeholder = "Port"
+		case userInput:
+			ti.Placeholder = "User"
+		case passwordInput:
+			ti.Placeholder = "Password"
+			ti.EchoMode = textinput.EchoPassword
+			ti.EchoCharacter = '•'
+		case databaseInput:
+			ti.Placeholder = "Database"
+		case optionsInput:
+			ti.Placeholder = "Options, e.g. sslmode=disable (optional)"
+			ti.CharLimit = 0
 		}
 
 		m.inputs[i] = ti // Assign the initialized textinput.Model back to the slice
@@ -88,6 +210,13 @@ func NewDBFormModel() *DBFormModel {
 
 func (m *DBFormModel) Reset() {
 	m.focusIndex = 0
+	m.err = nil
+	m.readOnly = false
+	m.storeSecret = false
+	m.editIndex = -1
+	m.title = "Add Connection"
+	m.driver = nil
+	m.clearTest()
 	for i := range m.inputs {
 		m.inputs[i].SetValue("")
 		if i == 0 {
@@ -98,6 +227,239 @@ func (m *DBFormModel) Reset() {
 	}
 }
 
+// Fills the form with a saved connection. Submitting it replaces the connection.
+func (m *DBFormModel) Edit(index int, item DBConnItems) {
+	m.Reset()
+	m.editIndex = index
+	m.title = "Edit Connection"
+	m.readOnly = item.Limits.ReadOnly
+
+	m.inputs[nameInput].SetValue(item.Name)
+	m.inputs[urlInput].SetValue(item.URL)
+	if item.Limits.StatementTimeout > 0 {
+		m.inputs[timeoutInput].SetValue(item.Limits.StatementTimeout.String())
+	}
+	if item.Limits.MaxRows > 0 {
+		m.inputs[maxRowsInput].SetValue(strconv.Itoa(item.Limits.MaxRows))
+	}
+	m.validateURL()
+}
+
+// Checks the URL as it is typed. A blank URL is only reported on submit.
+func (m *DBFormModel) validateURL() {
+	m.urlErr = nil
+	m.urlDriver = ""
+
+	if m.connectionInputsBlank() {
+		return
+	}
+
+	value, err := m.connectionURL()
+	if err != nil {
+		m.urlErr = err
+		return
+	}
+
+	driver, err := drivers.ValidateURL(value)
+	m.urlErr = err
+	if err == nil {
+		m.urlDriver = driver.Name
+	}
+}
+
+// Connects with the typed URL and limits in the background, like selecting the
+// connection would, and reports the outcome in the form
+func (m *DBFormModel) testConnection() tea.Cmd {
+	limits, err := m.limits()
+	m.err = err
+	if err != nil {
+		return nil
+	}
+
+	url, err := m.connectionURL()
+	if err == nil {
+		_, err = drivers.ValidateURL(url)
+	}
+	if err != nil {
+		m.urlErr = err
+		return nil
+	}
+
+	m.clearTest()
+	m.testing = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTestTimeout)
+	m.cancelTest = cancel
+	id := m.testID
+
+	return func() tea.Msg {
+		defer cancel()
+		return ConnectionTestedMsg{ID: id, Err: drivers.CheckConnection(ctx, url, limits)}
+	}
+}
+
+// Forgets the last test and abandons one that is still running
+func (m *DBFormModel) clearTest() {
+	if m.cancelTest != nil {
+		m.cancelTest()
+		m.cancelTest = nil
+	}
+
+	m.testID++
+	m.testing = false
+	m.tested = false
+	m.testErr = nil
+}
+
+// Inputs in the order tab moves through them
+func (m *DBFormModel) focusOrder() []int {
+	order := []int{nameInput}
+	order = append(order, connectionInputs[m.connectionFields()]...)
+	return append(order, timeoutInput, maxRowsInput, submitButton, testButton)
+}
+
+// Input or button that has the focus
+func (m *DBFormModel) focused() int {
+	return m.focusOrder()[m.focusIndex]
+}
+
+func (m *DBFormModel) updateFocus() {
+	focused := m.focused()
+	for i := range m.inputs {
+		if i == focused {
+			m.inputs[i].Focus()
+		} else {
+			m.inputs[i].Blur()
+		}
+	}
+}
+
+func (m *DBFormModel) connectionFields() drivers.ConnectionFields {
+	if m.driver == nil {
+		return drivers.URLOnly
+	}
+	return m.driver.Fields
+}
+
+func (m *DBFormModel) connectionInputsBlank() bool {
+	for _, i := range connectionInputs[m.connectionFields()] {
+		if strings.TrimSpace(m.inputs[i].Value()) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// The typed URL, or the URL built from the inputs of the chosen driver
+func (m *DBFormModel) connectionURL() (string, error) {
+	return m.connectionURLWithPassword(m.inputs[passwordInput].Value())
+}
+
+func (m *DBFormModel) connectionURLWithPassword(password string) (string, error) {
+	if m.driver == nil {
+		return m.inputs[urlInput].Value(), nil
+	}
+
+	value := func(i int) string {
+		return strings.TrimSpace(m.inputs[i].Value())
+	}
+
+	return drivers.BuildURL(*m.driver, drivers.URLParts{
+		Path:     value(pathInput),
+		Host:     value(hostInput),
+		Port:     value(portInput),
+		User:     value(userInput),
+		Password: password,
+		Database: value(databaseInput),
+		Options:  value(optionsInput),
+	})
+}
+
+// Moves on to the next driver that has its own inputs, and back to typing the
+// URL after the last one. What was entered so far carries over.
+func (m *DBFormModel) switchDriver() {
+	current, _ := m.connectionURL()
+
+	var choices []drivers.Driver
+	for _, driver := range m.drivers {
+		if driver.Fields != drivers.URLOnly {
+			choices = append(choices, driver)
+		}
+	}
+	if len(choices) == 0 {
+		return
+	}
+
+	var next *drivers.Driver
+	if m.driver == nil {
+		// Starts with the driver of the typed URL
+		next = &choices[0]
+		scheme, _, _ := strings.Cut(current, ":")
+		if driver, ok := drivers.Lookup(scheme); ok && driver.Fields != drivers.URLOnly {
+			next = &driver
+		}
+	} else {
+		for i, driver := range choices {
+			if driver.Scheme == m.driver.Scheme && i+1 < len(choices) {
+				next = &choices[i+1]
+			}
+		}
+	}
+
+	m.driver = next
+	if next == nil {
+		m.inputs[urlInput].SetValue(current)
+	} else if parts, err := drivers.SplitURL(*next, current); err == nil {
+		m.setURLParts(parts)
+	}
+
+	if next != nil {
+		m.inputs[portInput].Placeholder = "Port"
+		if next.DefaultPort != "" {
+			m.inputs[portInput].Placeholder += ", default " + next.DefaultPort
+		}
+	}
+
+	// Focus the first input of the driver
+	m.focusIndex = 1
+	m.updateFocus()
+	m.validateURL()
+	m.clearTest()
+}
+
+// Whether the typed password is stored as a secret on submit. Passwords that
+// already reference a secret stay as they are.
+func (m *DBFormModel) storesSecret() bool {
+	password := m.inputs[passwordInput].Value()
+	return m.storeSecret && m.connectionFields() == drivers.ServerFields &&
+		password != "" && !drivers.HasSecretReferences(password)
+}
+
+// Names a new secret after its connection, with a random suffix that keeps it unique
+func newSecretName(connection string) string {
+	slug := strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			return r
+		}
+		return '-'
+	}, strings.TrimSpace(connection))
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+
+	return slug + "-" + hex.EncodeToString(suffix)
+}
+
+func (m *DBFormModel) setURLParts(parts drivers.URLParts) {
+	m.inputs[pathInput].SetValue(parts.Path)
+	m.inputs[hostInput].SetValue(parts.Host)
+	m.inputs[portInput].SetValue(parts.Port)
+	m.inputs[userInput].SetValue(parts.User)
+	m.inputs[passwordInput].SetValue(parts.Password)
+	m.inputs[databaseInput].SetValue(parts.Database)
+	m.inputs[optionsInput].SetValue(parts.Options)
+}
+
 func (m *DBFormModel) Init() tea.Cmd {
 	return textinput.Blink
 }
@@ -106,69 +468,265 @@ func (m *DBFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
+	case ConnectionTestedMsg:
+		// Results of a test that was replaced or abandoned are dropped
+		if msg.ID == m.testID {
+			m.testing = false
+			m.tested = true
+			m.testErr = msg.Err
+			m.cancelTest = nil
+		}
+		return m, nil
+
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(msg, m.keys.CancelForm):
 			return m, func() tea.Msg {
 				return CancelFormMsg{}
 			}
+		case key.Matches(msg, m.keys.ToggleReadOnly):
+			m.readOnly = !m.readOnly
+			m.clearTest()
+			return m, nil
+
+		case key.Matches(msg, m.keys.SwitchDriver):
+			m.switchDriver()
+			return m, nil
+
+		case key.Matches(msg, m.keys.StoreSecret):
+			m.storeSecret = !m.storeSecret
+			return m, nil
+
 		case key.Matches(msg, m.keys.NextInput):
-			m.focusIndex = (m.focusIndex + 1) % (len(m.inputs) + 1)
+			m.focusIndex = (m.focusIndex + 1) % len(m.focusOrder())
 
 		case key.Matches(msg, m.keys.PrevInput):
-			m.focusIndex = (m.focusIndex - 1 + len(m.inputs) + 1) % (len(m.inputs) + 1)
+			count := len(m.focusOrder())
+			m.focusIndex = (m.focusIndex - 1 + count) % count
 
 		case key.Matches(msg, m.keys.SubmitForm):
-			if m.focusIndex == len(m.inputs) {
-				return m, func() tea.Msg {
-					return SubmitFormMsg{
-						Name: m.inputs[0].Value(),
-						URL:  m.inputs[1].Value(),
+			if m.focused() == testButton {
+				return m, m.testConnection()
+			}
+
+			if m.focused() == submitButton {
+				limits, err := m.limits()
+				var url string
+				if err == nil {
+					url, err = m.validate()
+				}
+				m.err = err
+				if err != nil {
+					return m, nil
+				}
+
+				submit := SubmitFormMsg{
+					Name:   m.inputs[nameInput].Value(),
+					URL:    url,
+					Limits: limits,
+					Edit:   m.editIndex >= 0,
+					Index:  m.editIndex,
+				}
+
+				// Each password gets its own secret, so connections with the same
+				// name can't overwrite each other's password
+				if m.storesSecret() {
+					name := newSecretName(submit.Name)
+					submit.URL, err = m.connectionURLWithPassword("${secret:" + name + "}")
+					if err != nil {
+						m.err = err
+						return m, nil
 					}
+					submit.Secrets = map[string]string{name: m.inputs[passwordInput].Value()}
 				}
-			}
-		}
 
-		// Update focus for inputs
-		for i := range m.inputs {
-			if i == m.focusIndex {
-				m.inputs[i].Focus()
-			} else {
-				m.inputs[i].Blur()
+				return m, func() tea.Msg {
+					return submit
+				}
 			}
 		}
+
+		m.updateFocus()
 	}
 
 	// Update all inputs
+	url, _ := m.connectionURL()
 	for i := range m.inputs {
 		var cmd tea.Cmd
 		m.inputs[i], cmd = m.inputs[i].Update(msg)
 		cmds = append(cmds, cmd)
 	}
 
+	// A test result no longer applies once the URL changed
+	if changed, _ := m.connectionURL(); changed != url {
+		m.validateURL()
+		m.clearTest()
+	}
+
 	return m, tea.Batch(cmds...)
 }
 
+// Checks the inputs that are required before the connection is saved and returns
+// its URL
+func (m *DBFormModel) validate() (string, error) {
+	if strings.TrimSpace(m.inputs[nameInput].Value()) == "" {
+		return "", errors.New("Enter a connection name")
+	}
+
+	url, err := m.connectionURL()
+	if err == nil {
+		_, err = drivers.ValidateURL(url)
+	}
+	m.urlErr = err
+	return url, err
+}
+
+// Parses the optional safety settings, blank inputs mean no limit
+func (m *DBFormModel) limits() (drivers.Limits, error) {
+	limits := drivers.Limits{ReadOnly: m.readOnly}
+
+	if value := strings.TrimSpace(m.inputs[timeoutInput].Value()); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return limits, fmt.Errorf("Invalid statement timeout %q, use a duration such as 30s or 2m", value)
+		}
+		limits.StatementTimeout = timeout
+	}
+
+	if value := strings.TrimSpace(m.inputs[maxRowsInput].Value()); value != "" {
+		maxRows, err := strconv.Atoi(value)
+		if err != nil || maxRows < 0 {
+			return limits, fmt.Errorf("Invalid max rows %q, use a whole number", value)
+		}
+		limits.MaxRows = maxRows
+	}
+
+	return limits, nil
+}
+
 func (m *DBFormModel) View() string {
 	var output string
 
 	output += formTitleStyle.Render(m.title) + "\n"
 
+	// Connection type, switched with a key so tab keeps moving through the inputs
+	connectionType := "URL"
+	if m.driver != nil {
+		connectionType = m.driver.Name
+	}
+	output += formBlurredStyle.Render("Type: "+connectionType) + formHintStyle.Render("("+m.keys.SwitchDriver.Help().Key+")") + "\n"
+
 	// Input fields
-	for i := range m.inputs {
-		if i == m.focusIndex {
+	order := m.focusOrder()
+	for position, i := range order {
+		if i >= formInputCount {
+			continue
+		}
+
+		if position == m.focusIndex {
 			output += formFocusedStyle.Render(m.inputs[i].View()) + "\n"
 		} else {
 			output += formBlurredStyle.Render(m.inputs[i].View()) + "\n"
 		}
+
+		// The URL is checked as it is typed, below the last input it is built from
+		if inputs := connectionInputs[m.connectionFields()]; i == inputs[len(inputs)-1] {
+			output += m.urlStatusView()
+		}
 	}
 
+	// Only passwords entered in their own input can be stored
+	if m.connectionFields() == drivers.ServerFields {
+		storeSecret := "[ ] Store password encrypted"
+		if m.storeSecret {
+			storeSecret = "[x] Store password encrypted"
+		}
+		output += formBlurredStyle.Render(storeSecret) + formHintStyle.Render("("+m.keys.StoreSecret.Help().Key+")") + "\n"
+	}
+
+	// Read-only toggle
+	readOnly, readOnlyStyle := "[ ] Read only", formBlurredStyle
+	if m.readOnly {
+		readOnly, readOnlyStyle = "[x] Read only", formSchemeStyle.Padding(0, 1)
+	}
+	output += readOnlyStyle.Render(readOnly) + formHintStyle.Render("("+m.keys.ToggleReadOnly.Help().Key+")") + "\n"
+
 	// Button field
-	if m.focusIndex == len(m.inputs) { // Focused state for submit button
+	if m.focused() == submitButton { // Focused state for submit button
 		output += formSubmitStyle.Render("\n[ Submit ]\n")
 	} else {
 		output += formBlurredSubmit.Render("\nSubmit\n")
 	}
 
+	if m.focused() == testButton {
+		output += formSubmitStyle.Render("[ Test ]") + "\n"
+	} else {
+		output += formBlurredSubmit.Render("Test") + "\n"
+	}
+
+	// Outcome of the Test button
+	switch {
+	case m.testing:
+		output += formHintStyle.Padding(0, 1).Render("Testing connection...") + "\n"
+	case m.tested && m.testErr != nil:
+		output += formErrorStyle.Render(m.testErr.Error()) + "\n"
+	case m.tested:
+		output += formSchemeStyle.Padding(0, 1).Render("✓ Connection OK") + "\n"
+	}
+
+	if m.err != nil {
+		output += formErrorStyle.Render(m.err.Error()) + "\n"
+	}
+
+	output += m.supportedDriversView()
+
+	return output
+}
+
+// Shows the URL built from the inputs, with the password hidden, and whether it is valid
+func (m *DBFormModel) urlStatusView() string {
+	var output string
+
+	if m.driver != nil && !m.connectionInputsBlank() {
+		if value, err := m.connectionURL(); err == nil {
+			if parsed, err := url.Parse(value); err == nil {
+				value = parsed.Redacted()
+			}
+			output += formHintStyle.Padding(0, 1).Render(value) + "\n"
+		}
+	}
+
+	switch {
+	case m.urlErr != nil:
+		output += formErrorStyle.Render(m.urlErr.Error()) + "\n"
+	case m.urlDriver != "":
+		output += formSchemeStyle.Padding(0, 1).Render("✓ "+m.urlDriver) + "\n"
+	}
+
 	return output
 }
+
+// Lists the registered drivers and an example URL for each
+func (m *DBFormModel) supportedDriversView() string {
+	if len(m.drivers) == 0 {
+		return ""
+	}
+
+	schemes := make([]string, len(m.drivers))
+	width := 0
+	for i, driver := range m.drivers {
+		schemes[i] = strings.Join(append([]string{driver.Scheme}, driver.Aliases...), ", ")
+		width = max(width, len(schemes[i]))
+	}
+
+	var b strings.Builder
+	b.WriteString("\n" + formTitleStyle.Render("Supported databases") + "\n")
+	for i, driver := range m.drivers {
+		b.WriteString(fmt.Sprintf("  %s  %s\n",
+			formSchemeStyle.Render(fmt.Sprintf("%-*s", width, schemes[i])),
+			formHintStyle.Render(driver.ExampleURL)))
+	}
+	b.WriteString(formHintStyle.Render("  ${VAR} and ${secret:name} are filled in when connecting") + "\n")
+
+	return b.String()
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/jdkingsbury/americano/internal/config"
 	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/jdkingsbury/americano/msgtypes"
 )
 
 const listHeight = 14
@@ -24,6 +26,7 @@ var (
 type DBConnItems struct {
 	Name     string
 	URL      string
+	Limits   drivers.Limits
 	isButton bool
 }
 
@@ -41,6 +44,9 @@ func (d listItemDelegate) Render(w io.Writer, m list.Model, index int, listItem
 	}
 
 	str := fmt.Sprintf("%s", i.Name)
+	if i.Limits.ReadOnly {
+		str += " [RO]"
+	}
 
 	fn := listItemStyle.Render
 	if index == m.Index() {
@@ -52,14 +58,11 @@ func (d listItemDelegate) Render(w io.Writer, m list.Model, index int, listItem
 	fmt.Fprint(w, fn(str))
 }
 
-type SetupEditorPaneMsg struct {
-	dbURL string
-	DB    *drivers.Database
-}
-
-type SetupDBTreeMsg struct {
-	dbURL string
-	DB    *drivers.Database
+// Asks the layout to open a session for the selected connection
+type ConnectMsg struct {
+	Name   string
+	URL    string
+	Limits drivers.Limits
 }
 
 type DBConnModel struct {
@@ -68,10 +71,28 @@ type DBConnModel struct {
 	focusIndex int
 	database   drivers.Database
 	keys       dbConnKeyMaps
+	// The connections are saved here after every change. Empty keeps them in memory only.
+	configPath string
+}
+
+// Asks the sidebar to open the form for editing the connection at Index
+type EditConnectionMsg struct {
+	Index int
+	Item  DBConnItems
+}
+
+// Sent once deleting the connection at Index has been confirmed
+type DeleteConnectionMsg struct {
+	Index int
 }
 
 type dbConnKeyMaps struct {
-	Select key.Binding
+	Select    key.Binding
+	Edit      key.Binding
+	Delete    key.Binding
+	Duplicate key.Binding
+	MoveUp    key.Binding
+	MoveDown  key.Binding
 }
 
 func newDBConnKeyMap() dbConnKeyMaps {
@@ -80,9 +101,33 @@ func newDBConnKeyMap() dbConnKeyMaps {
 			key.WithKeys("enter"),
 			key.WithHelp("enter", "select item"),
 		),
+		Edit: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "edit connection"),
+		),
+		Delete: key.NewBinding(
+			key.WithKeys("x", "delete"),
+			key.WithHelp("x", "delete connection"),
+		),
+		Duplicate: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "duplicate connection"),
+		),
+		MoveUp: key.NewBinding(
+			key.WithKeys("K", "shift+up"),
+			key.WithHelp("K", "move connection up"),
+		),
+		MoveDown: key.NewBinding(
+			key.WithKeys("J", "shift+down"),
+			key.WithHelp("J", "move connection down"),
+		),
 	}
 }
 
+func (m *DBConnModel) KeyMap() []key.Binding {
+	return []key.Binding{m.keys.Edit, m.keys.Delete, m.keys.Duplicate, m.keys.MoveUp, m.keys.MoveDown}
+}
+
 func NewDBConnModel(width int) *DBConnModel {
 	items := []list.Item{
 		DBConnItems{Name: "󰆺 Add Connection", URL: "", isButton: true},
@@ -104,8 +149,123 @@ func NewDBConnModel(width int) *DBConnModel {
 	return pane
 }
 
-func (m *DBConnModel) AddConnection(name, url string) {
-	m.list.InsertItem(len(m.list.Items()), DBConnItems{Name: name, URL: url, isButton: false})
+func (m *DBConnModel) AddConnection(name, url string, limits drivers.Limits) {
+	m.list.InsertItem(len(m.list.Items()), DBConnItems{Name: name, URL: url, Limits: limits, isButton: false})
+}
+
+// Adds the connections saved in the config file and saves every later change to it
+func (m *DBConnModel) LoadConnections(path string) error {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	m.configPath = path
+	for _, connection := range cfg.Connections {
+		m.AddConnection(connection.Name, connection.URL, connection.Limits())
+	}
+
+	return nil
+}
+
+// Saved connections in list order
+func (m *DBConnModel) Connections() []DBConnItems {
+	var connections []DBConnItems
+	for _, item := range m.list.Items() {
+		if item, ok := item.(DBConnItems); ok && !item.isButton {
+			connections = append(connections, item)
+		}
+	}
+
+	return connections
+}
+
+// Writes the connections to the config file, if there is one
+func (m *DBConnModel) saveConnections() tea.Cmd {
+	if m.configPath == "" {
+		return nil
+	}
+
+	cfg := config.Config{}
+	for _, item := range m.Connections() {
+		cfg.Connections = append(cfg.Connections, config.NewConnection(item.Name, item.URL, item.Limits))
+	}
+
+	if err := config.Save(m.configPath, cfg); err != nil {
+		return func() tea.Msg {
+			return msgtypes.NewErrMsg(fmt.Errorf("Failed to save connections: %w", err))
+		}
+	}
+
+	return nil
+}
+
+// Replaces the connection at index, e.g. after it was edited
+func (m *DBConnModel) UpdateConnection(index int, name, url string, limits drivers.Limits) {
+	if _, ok := m.connectionAt(index); ok {
+		m.list.SetItem(index, DBConnItems{Name: name, URL: url, Limits: limits})
+	}
+}
+
+func (m *DBConnModel) RemoveConnection(index int) {
+	if _, ok := m.connectionAt(index); ok {
+		m.list.RemoveItem(index)
+		m.list.Select(min(index, len(m.list.Items())-1))
+	}
+}
+
+// Inserts a copy of the connection at index below it, named e.g. "prod (copy)"
+func (m *DBConnModel) DuplicateConnection(index int) {
+	item, ok := m.connectionAt(index)
+	if !ok {
+		return
+	}
+
+	taken := map[string]bool{}
+	for _, connection := range m.Connections() {
+		taken[connection.Name] = true
+	}
+
+	name := item.Name + " (copy)"
+	for n := 2; taken[name]; n++ {
+		name = fmt.Sprintf("%s (copy %d)", item.Name, n)
+	}
+
+	item.Name = name
+	m.list.InsertItem(index+1, item)
+	m.list.Select(index + 1)
+}
+
+// Moves the connection at index up or down by offset, keeping it selected.
+// The Add Connection button stays at the top.
+func (m *DBConnModel) MoveConnection(index, offset int) bool {
+	item, ok := m.connectionAt(index)
+	if !ok {
+		return false
+	}
+
+	target := index + offset
+	other, ok := m.connectionAt(target)
+	if !ok {
+		return false
+	}
+
+	m.list.SetItem(target, item)
+	m.list.SetItem(index, other)
+	m.list.Select(target)
+
+	return true
+}
+
+// Saved connection at a list index, the Add Connection button is not one
+func (m *DBConnModel) connectionAt(index int) (DBConnItems, bool) {
+	items := m.list.Items()
+	if index < 0 || index >= len(items) {
+		return DBConnItems{}, false
+	}
+
+	item, ok := items[index].(DBConnItems)
+	return item, ok && !item.isButton
 }
 
 func (m *DBConnModel) FocusedOnButton() bool {
@@ -127,17 +287,55 @@ func (m *DBConnModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			if item.URL != "" {
-				setupDBTreeCmd := func() tea.Msg {
-					return SetupDBTreeMsg{dbURL: item.URL}
+				return m, func() tea.Msg {
+					return ConnectMsg{Name: item.Name, URL: item.URL, Limits: item.Limits}
 				}
+			}
 
-				setupEditorCmd := func() tea.Msg {
-					return SetupEditorPaneMsg{dbURL: item.URL}
+		case key.Matches(msg, m.keys.Edit):
+			index := m.list.Index()
+			if item, ok := m.connectionAt(index); ok {
+				return m, func() tea.Msg {
+					return EditConnectionMsg{Index: index, Item: item}
+				}
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.Delete):
+			index := m.list.Index()
+			if item, ok := m.connectionAt(index); ok {
+				return m, func() tea.Msg {
+					return ConfirmMsg{
+						Prompt: fmt.Sprintf("Delete connection %s?", item.Name),
+						Msg:    DeleteConnectionMsg{Index: index},
+					}
 				}
+			}
+			return m, nil
 
-				return m, tea.Batch(setupDBTreeCmd, setupEditorCmd)
+		case key.Matches(msg, m.keys.Duplicate):
+			if _, ok := m.connectionAt(m.list.Index()); ok {
+				m.DuplicateConnection(m.list.Index())
+				return m, m.saveConnections()
 			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.MoveUp):
+			if m.MoveConnection(m.list.Index(), -1) {
+				return m, m.saveConnections()
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.MoveDown):
+			if m.MoveConnection(m.list.Index(), 1) {
+				return m, m.saveConnections()
+			}
+			return m, nil
 		}
+
+	case DeleteConnectionMsg:
+		m.RemoveConnection(msg.Index)
+		return m, m.saveConnections()
 	}
 
 	m.list, cmd = m.list.Update(msg)
@@ -1,12 +1,15 @@
 package panes
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/jdkingsbury/americano/msgtypes"
 )
 
 var (
@@ -20,16 +23,45 @@ const (
 	closedCaret = "▸" // Rightward caret for closed state
 )
 
+// How long loading the tree or the schema of a table may take
+const treeLoadTimeout = 10 * time.Second
+
 type DBTreeMsg struct {
 	Notification string
 	Error        error
 }
 
+// Sent once a database has been attached or detached, the tree is reloaded on success
+type SchemaChangedMsg struct {
+	Notification string
+	Error        error
+}
+
+// Sent once the tables, views and triggers of a connection have been loaded in the
+// background. A reload leaves the table list open.
+type TreeLoadedMsg struct {
+	db     drivers.Database
+	Name   string
+	Items  []ListItem
+	reload bool
+}
+
+// Sent once the schema of an opened table has been loaded in the background
+type TableSchemaLoadedMsg struct {
+	db    drivers.Database
+	Table drivers.TableName
+	Items []ListItem
+}
+
 type ListItem struct {
 	Title    string
 	SubItems []ListItem
 	IsOpen   bool
 	Query    string
+	// Set on table items. Their columns, keys and indexes are loaded when first opened.
+	Table drivers.TableName
+	// Set on the schema groups of the table list
+	Schema string
 }
 
 // FlatListItem is used for the rendering the list items
@@ -38,58 +70,74 @@ type FlatListItem struct {
 	Level     int
 	IsOpen    bool
 	IsSubItem bool
+	// Indices leading to the item in the original list
+	Path []int
 }
 
 type DBTreeModel struct {
+	db           drivers.Database
 	originalList []ListItem
 	flatList     []FlatListItem
 	cursor       int
 }
 
+// The tree of a connection starts out loading, Init loads it in the background
 func NewDBTreeModel(db drivers.Database) *DBTreeModel {
-	var originalList []ListItem
-
-	if db == nil {
-		originalList = []ListItem{
-			{Title: "No connection"},
-		}
-	} else {
-		dbName, err := db.GetDatabaseName()
-		if err != nil {
-			dbName = "Unknown db"
-		}
-
-		originalList = []ListItem{
-			{
-				Title:    dbName,
-				SubItems: buildDBTree(db),
-				IsOpen:   false,
-			},
-		}
+	originalList := []ListItem{
+		{Title: "No connection"},
+	}
+	if db != nil {
+		originalList[0].Title = "Loading..."
 	}
 
-	flatList := flattenList(originalList, 0)
+	flatList := flattenList(originalList, 0, nil)
 
 	return &DBTreeModel{
+		db:           db,
 		originalList: originalList,
 		flatList:     flatList,
 		cursor:       0,
 	}
 }
 
+// Loads the tree in the background, see TreeLoadedMsg
+func (m *DBTreeModel) loadTree(reload bool) tea.Cmd {
+	db := m.db
+	if db == nil {
+		return nil
+	}
+
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), treeLoadTimeout)
+		defer cancel()
+
+		name, err := db.GetDatabaseName(ctx)
+		if err != nil {
+			name = "Unknown db"
+		}
+
+		return TreeLoadedMsg{db: db, Name: name, Items: buildDBTree(ctx, db), reload: reload}
+	}
+}
+
 // TODO: Work on adding saved queries table when save query feature is added
-func buildDBTree(db drivers.Database) []ListItem {
-	tables, err := db.GetTables()
+func buildDBTree(ctx context.Context, db drivers.Database) []ListItem {
+	tables, err := db.GetTables(ctx)
 	if err != nil {
 		return []ListItem{
 			{Title: "No connection"},
 		}
 	}
 
+	defaultSchema := "default"
+	if namer, ok := db.(drivers.SchemaNamer); ok {
+		defaultSchema = namer.DefaultSchema()
+	}
+
 	tablesItem := ListItem{
 		Title:    "Tables",
 		IsOpen:   false,
-		SubItems: buildTableList(tables),
+		SubItems: buildTableList(tables, defaultSchema),
 	}
 
 	// savedQueriesItem := ListItem {
@@ -97,99 +145,356 @@ func buildDBTree(db drivers.Database) []ListItem {
 	//   IsOpen: false,
 	// }
 
-	return []ListItem{
+	items := []ListItem{
 		tablesItem,
 		// savedQueriesItem,
 	}
+
+	// Views and triggers are left out when the driver can't list them
+	if !drivers.Supports(db, drivers.CapViewsAndTriggers) {
+		return items
+	}
+	if views, err := db.GetViews(ctx); err == nil && len(views) > 0 {
+		items = append(items, ListItem{Title: "Views", SubItems: buildViewList(views, drivers.DialectOf(db))})
+	}
+	if triggers, err := db.GetTriggers(ctx); err == nil && len(triggers) > 0 {
+		items = append(items, ListItem{Title: "Triggers", SubItems: buildTriggerList(triggers)})
+	}
+
+	return items
 }
 
-func buildTableList(tables []string) []ListItem {
-	var tableItems []ListItem
+// Tables are grouped by schema once any of them is outside the default schema, e.g. the
+// tables of an attached sqlite database
+func buildTableList(tables []drivers.TableName, defaultSchema string) []ListItem {
+	grouped := false
 	for _, table := range tables {
-		tableItems = append(tableItems, ListItem{
-			Title:    table,
-			SubItems: buildTableSubItems(table),
-			IsOpen:   false,
+		if table.Schema != "" {
+			grouped = true
+			break
+		}
+	}
+
+	if !grouped {
+		var tableItems []ListItem
+		for _, table := range tables {
+			tableItems = append(tableItems, ListItem{
+				Title:  table.Name,
+				Table:  table,
+				IsOpen: false,
+			})
+		}
+
+		return tableItems
+	}
+
+	// Tables without a schema belong to the default schema, which is listed first
+	schemaItems := []ListItem{{Title: defaultSchema, Schema: defaultSchema}}
+	for _, table := range tables {
+		schema, name := table.Schema, table.Name
+		if schema == "" {
+			schema = defaultSchema
+		}
+
+		i := 0
+		for i < len(schemaItems) && schemaItems[i].Schema != schema {
+			i++
+		}
+		if i == len(schemaItems) {
+			schemaItems = append(schemaItems, ListItem{Title: schema, Schema: schema})
+		}
+
+		schemaItems[i].SubItems = append(schemaItems[i].SubItems, ListItem{Title: name, Table: table})
+	}
+
+	if len(schemaItems[0].SubItems) == 0 {
+		schemaItems = schemaItems[1:]
+	}
+
+	return schemaItems
+}
+
+// Sub items of a table: a query listing its rows followed by its schema
+func buildTableSubItems(ctx context.Context, db drivers.Database, table drivers.TableName) []ListItem {
+	items := []ListItem{
+		{Title: " list", Query: fmt.Sprintf("SELECT * FROM %s;", drivers.DialectOf(db).QuoteTable(table))},
+	}
+
+	columns, err := db.GetColumns(ctx, table)
+	if err != nil {
+		return append(items, ListItem{Title: fmt.Sprintf("Error: %v", err)})
+	}
+
+	columnItems := make([]ListItem, len(columns))
+	for i, column := range columns {
+		columnItems[i] = ListItem{Title: formatColumn(column)}
+	}
+	items = append(items, ListItem{Title: "Columns", SubItems: columnItems})
+
+	var keyItems []ListItem
+	if primaryKey := drivers.PrimaryKey(columns); len(primaryKey) > 0 {
+		keyItems = append(keyItems, ListItem{Title: fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKey, ", "))})
+	}
+	if foreignKeys, err := db.GetForeignKeys(ctx, table); err != nil {
+		keyItems = append(keyItems, ListItem{Title: fmt.Sprintf("Error: %v", err)})
+	} else {
+		for _, fk := range foreignKeys {
+			keyItems = append(keyItems, ListItem{Title: formatForeignKey(fk)})
+		}
+	}
+	if len(keyItems) > 0 {
+		items = append(items, ListItem{Title: "Keys", SubItems: keyItems})
+	}
+
+	if indexes, err := db.GetIndexes(ctx, table); err != nil {
+		items = append(items, ListItem{Title: fmt.Sprintf("Error: %v", err)})
+	} else if len(indexes) > 0 {
+		indexItems := make([]ListItem, len(indexes))
+		for i, index := range indexes {
+			indexItems[i] = ListItem{Title: formatIndex(index)}
+		}
+		items = append(items, ListItem{Title: "Indexes", SubItems: indexItems})
+	}
+
+	return items
+}
+
+func buildViewList(views []drivers.View, dialect drivers.Dialect) []ListItem {
+	var viewItems []ListItem
+	for _, view := range views {
+		viewItems = append(viewItems, ListItem{
+			Title: view.String(),
+			Query: fmt.Sprintf("SELECT * FROM %s;", dialect.QuoteTable(view.TableName)),
 		})
 	}
 
-	return tableItems
+	return viewItems
 }
 
-// Sub items containing queries for tables
-func buildTableSubItems(table string) []ListItem {
-	return []ListItem{
-		{Title: " list", Query: fmt.Sprintf("SELECT * FROM %s;", table)},
-		{Title: " column", Query: fmt.Sprintf("PRAGMA table_info(%s);", table)},
-		{Title: " foreign key", Query: fmt.Sprintf("PRAGMA foreign_key_list(%s);", table)},
+// Selecting a trigger inserts its definition into the editor
+func buildTriggerList(triggers []drivers.Trigger) []ListItem {
+	var triggerItems []ListItem
+	for _, trigger := range triggers {
+		triggerItems = append(triggerItems, ListItem{
+			Title: fmt.Sprintf("%s (%s)", trigger.Name, trigger.Table),
+			Query: trigger.Definition,
+		})
+	}
+
+	return triggerItems
+}
+
+// e.g. age INTEGER NOT NULL DEFAULT 0
+func formatColumn(column drivers.Column) string {
+	parts := []string{column.Name}
+	if column.Type != "" {
+		parts = append(parts, column.Type)
 	}
+	if column.PrimaryKey > 0 {
+		parts = append(parts, "PK")
+	}
+	if !column.Nullable {
+		parts = append(parts, "NOT NULL")
+	}
+	if column.HasDefault {
+		parts = append(parts, "DEFAULT "+column.Default)
+	}
+
+	return strings.Join(parts, " ")
 }
 
-func flattenList(items []ListItem, level int) []FlatListItem {
+// e.g. FK (author_id) → authors(id) ON DELETE CASCADE
+func formatForeignKey(fk drivers.ForeignKey) string {
+	title := fmt.Sprintf("FK (%s) → %s(%s)", strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", "))
+	if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+		title += " ON UPDATE " + fk.OnUpdate
+	}
+	if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+		title += " ON DELETE " + fk.OnDelete
+	}
+
+	return title
+}
+
+// e.g. idx_users_email (email) UNIQUE
+func formatIndex(index drivers.Index) string {
+	title := fmt.Sprintf("%s (%s)", index.Name, strings.Join(index.Columns, ", "))
+	if index.Primary {
+		title += " PRIMARY"
+	} else if index.Unique {
+		title += " UNIQUE"
+	}
+
+	return title
+}
+
+func flattenList(items []ListItem, level int, parentPath []int) []FlatListItem {
 	var flatList []FlatListItem
-	for _, item := range items {
+	for i, item := range items {
+		path := append(append([]int{}, parentPath...), i)
 		flatItem := FlatListItem{
 			Title:     item.Title,
 			Level:     level,
 			IsOpen:    item.IsOpen,
-			IsSubItem: len(item.SubItems) > 0,
+			IsSubItem: len(item.SubItems) > 0 || item.Table.Name != "",
+			Path:      path,
 		}
 		flatList = append(flatList, flatItem)
 
 		// If the item is open and has subitems, recursively flatten the subitems
 		if item.IsOpen && len(item.SubItems) > 0 {
-			flatList = append(flatList, flattenList(item.SubItems, level+1)...)
+			flatList = append(flatList, flattenList(item.SubItems, level+1, path)...)
 		}
 	}
 	return flatList
 }
 
-// Toggles and item's open/collapse state and rebuilds the flat list
-func (m *DBTreeModel) toggleItemOpen() {
-	// Find the item in the original list
-	m.updateOriginalListState(m.originalList, m.flatList[m.cursor].Title, 0, m.flatList[m.cursor].Level)
+// Returns the item in the original list at the given path
+func (m *DBTreeModel) itemAt(path []int) *ListItem {
+	items := m.originalList
+	var item *ListItem
+	for _, i := range path {
+		if i >= len(items) {
+			return nil
+		}
+		item = &items[i]
+		items = item.SubItems
+	}
+	return item
+}
+
+// Toggles and item's open/collapse state and rebuilds the flat list. Table schemas are
+// loaded in the background the first time they are opened.
+func (m *DBTreeModel) toggleItemOpen() tea.Cmd {
+	item := m.itemAt(m.flatList[m.cursor].Path)
+	if item == nil {
+		return nil
+	}
+
+	var cmd tea.Cmd
+	if item.Table.Name != "" && item.SubItems == nil && m.db != nil {
+		item.SubItems = []ListItem{{Title: "Loading..."}}
+		cmd = m.loadTableSchema(item.Table)
+	}
+
+	item.IsOpen = !item.IsOpen
 
 	// Rebuild the flat list based on the updated original list
-	m.flatList = flattenList(m.originalList, 0)
+	m.flatList = flattenList(m.originalList, 0, nil)
+
+	return cmd
+}
+
+func (m *DBTreeModel) loadTableSchema(table drivers.TableName) tea.Cmd {
+	db := m.db
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), treeLoadTimeout)
+		defer cancel()
+
+		return TableSchemaLoadedMsg{db: db, Table: table, Items: buildTableSubItems(ctx, db, table)}
+	}
 }
 
-func getQueryForItem(title string, items []ListItem) string {
-	for _, item := range items {
-		if item.Title == title && item.Query != "" {
-			return item.Query
+// Returns the table item of the tree, nil once it is gone, e.g. after a reload
+func findTableItem(items []ListItem, table drivers.TableName) *ListItem {
+	for i := range items {
+		if items[i].Table == table {
+			return &items[i]
 		}
-		if len(item.SubItems) > 0 {
-			query := getQueryForItem(title, item.SubItems)
-			if query != "" {
-				return query
-			}
+		if item := findTableItem(items[i].SubItems, table); item != nil {
+			return item
 		}
 	}
-	return ""
+	return nil
 }
 
-// NOTE: Function is returning a bool to ensure recursion stops when found and also for testing when tests are added.
+// Whether databases can be attached to the connection, e.g. sqlite's ATTACH
+func (m *DBTreeModel) CanAttach() bool {
+	_, ok := m.db.(drivers.Attacher)
+	return ok
+}
 
-// Update the collapsible state and return true or false if found.
-func (m *DBTreeModel) updateOriginalListState(items []ListItem, title string, currentLevel, targetLevel int) bool {
-	for i := range items {
-		// Check if this is the correct item based on the title and level
-		if items[i].Title == title && currentLevel == targetLevel {
-			// Toggle open state
-			items[i].IsOpen = !items[i].IsOpen
-			return true // Exit after toggling
+// Attaches a database file in the background
+func (m *DBTreeModel) attach(path, schema string) tea.Cmd {
+	attacher, ok := m.db.(drivers.Attacher)
+	if !ok {
+		return nil
+	}
+
+	return func() tea.Msg {
+		if err := attacher.Attach(context.Background(), path, schema); err != nil {
+			return SchemaChangedMsg{Error: err}
 		}
+		return SchemaChangedMsg{Notification: fmt.Sprintf("Attached %s", path)}
+	}
+}
+
+// Reports attachments that a connection failed to attach while a query ran. The driver
+// dropped them, so the tree is reloaded without them.
+func (m *DBTreeModel) checkDroppedAttachments() tea.Cmd {
+	attacher, ok := m.db.(drivers.Attacher)
+	if !ok {
+		return nil
+	}
+
+	err := attacher.DroppedAttachments()
+	if err == nil {
+		return nil
+	}
+
+	return tea.Batch(m.reload(), func() tea.Msg {
+		return msgtypes.NewErrMsg(err)
+	})
+}
+
+// Detaches the database of the selected schema group
+func (m *DBTreeModel) detachSelected() tea.Cmd {
+	attacher, ok := m.db.(drivers.Attacher)
+	if !ok || len(m.flatList) == 0 {
+		return nil
+	}
+
+	item := m.itemAt(m.flatList[m.cursor].Path)
+	if item == nil || item.Schema == "" {
+		return nil
+	}
 
-		// Recursively check subitems if they exist
-		if len(items[i].SubItems) > 0 {
-			// If the item is found in the sublist, return true to stop recursion
-			if m.updateOriginalListState(items[i].SubItems, title, currentLevel+1, targetLevel) {
-				return true
+	schema := item.Schema
+	for _, attachment := range attacher.Attachments() {
+		if attachment.Schema == schema {
+			return func() tea.Msg {
+				if err := attacher.Detach(context.Background(), schema); err != nil {
+					return SchemaChangedMsg{Error: err}
+				}
+				return SchemaChangedMsg{Notification: fmt.Sprintf("Detached %s", schema)}
 			}
 		}
 	}
 
-	return false // Return false if the item was not found in the branch
+	return func() tea.Msg {
+		return msgtypes.NewErrMsg(fmt.Errorf("%s is not an attached database", schema))
+	}
+}
+
+// Loads the tree again after the schema changed, leaving the table list open
+func (m *DBTreeModel) reload() tea.Cmd {
+	return m.loadTree(true)
+}
+
+// Replaces the tree with what was loaded for the connection
+func (m *DBTreeModel) applyTree(msg TreeLoadedMsg) {
+	root := &m.originalList[0]
+	root.Title = msg.Name
+	root.SubItems = msg.Items
+	if msg.reload {
+		root.IsOpen = true
+		if len(root.SubItems) > 0 {
+			root.SubItems[0].IsOpen = true
+		}
+	}
+
+	m.flatList = flattenList(m.originalList, 0, nil)
+	m.cursor = min(m.cursor, len(m.flatList)-1)
 }
 
 func renderFlatList(flatList []FlatListItem, cursor int) string {
@@ -220,6 +525,37 @@ func renderFlatList(flatList []FlatListItem, cursor int) string {
 
 func (m *DBTreeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case SchemaChangedMsg:
+		if msg.Error != nil {
+			return m, func() tea.Msg {
+				return msgtypes.NewErrMsg(msg.Error)
+			}
+		}
+
+		return m, tea.Batch(m.reload(), func() tea.Msg {
+			return msgtypes.NewNotificationMsg(msg.Notification)
+		})
+
+	case TreeLoadedMsg:
+		// The tree of a previous connection is dropped
+		if msg.db == m.db {
+			m.applyTree(msg)
+		}
+		return m, nil
+
+	case TableSchemaLoadedMsg:
+		// The schema of a previous connection is dropped
+		if msg.db != m.db {
+			return m, nil
+		}
+
+		if item := findTableItem(m.originalList, msg.Table); item != nil {
+			item.SubItems = msg.Items
+			m.flatList = flattenList(m.originalList, 0, nil)
+			m.cursor = min(m.cursor, len(m.flatList)-1)
+		}
+		return m, nil
+
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "up", "k":
@@ -234,14 +570,17 @@ func (m *DBTreeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "enter", " ":
 			// Check if the selected item has an associated query
-			query := getQueryForItem(m.flatList[m.cursor].Title, m.originalList)
+			query := ""
+			if item := m.itemAt(m.flatList[m.cursor].Path); item != nil {
+				query = item.Query
+			}
 			if query != "" {
 				// Send the message with the query for the editor
 				return m, func() tea.Msg {
 					return InsertQueryMsg{Query: query}
 				}
 			} else {
-				m.toggleItemOpen()
+				return m, m.toggleItemOpen()
 			}
 		}
 	}
@@ -249,7 +588,7 @@ func (m *DBTreeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *DBTreeModel) Init() tea.Cmd {
-	return nil
+	return m.loadTree(false)
 }
 
 func (m *DBTreeModel) View() string {
@@ -1,11 +1,17 @@
 package panes
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/jdkingsbury/americano/msgtypes"
 )
 
 /* Handles The SQL Editor Pane*/
@@ -14,6 +20,21 @@ type InsertQueryMsg struct {
 	Query string
 }
 
+// Sent when the editor starts running a query
+type QueryStartedMsg struct {
+	ID        int
+	StartedAt time.Time
+}
+
+// Asks the editor to cancel the query that is currently running
+type CancelQueryMsg struct{}
+
+// Sent when the open transaction has been committed or rolled back
+type TransactionEndedMsg struct {
+	Committed bool
+	Err       error
+}
+
 type EditorPaneModel struct {
 	styles       lipgloss.Style
 	activeStyles lipgloss.Style
@@ -25,10 +46,30 @@ type EditorPaneModel struct {
 	isActive     bool
 	db           drivers.Database
 	keys         editorKeyMap
+	running      bool
+	queryID      int
+	cancelQuery  context.CancelFunc
+	stopOnError  bool
+	// Statements run in a transaction that is only committed on request
+	manualCommit bool
+	// Open while asking for the values of a query's placeholders
+	paramForm *ParamFormModel
+	// The open parameter form is for explaining the query rather than running it
+	explainParams bool
+	// Last values given for each parameterized query
+	paramValues map[string]map[string]string
+	// Shows the read-only badge
+	readOnly bool
 }
 
 type editorKeyMap struct {
-	ExecuteQuery key.Binding
+	ExecuteQuery  key.Binding
+	ExplainQuery  key.Binding
+	CancelQuery   key.Binding
+	ToggleOnError key.Binding
+	ManualCommit  key.Binding
+	Commit        key.Binding
+	Rollback      key.Binding
 }
 
 func newEditorPaneKeymap() editorKeyMap {
@@ -37,6 +78,30 @@ func newEditorPaneKeymap() editorKeyMap {
 			key.WithKeys("ctrl+e"),
 			key.WithHelp("ctrl+e", "execute query"),
 		),
+		ExplainQuery: key.NewBinding(
+			key.WithKeys("ctrl+g"),
+			key.WithHelp("ctrl+g", "explain query plan"),
+		),
+		CancelQuery: key.NewBinding(
+			key.WithKeys("ctrl+c"),
+			key.WithHelp("ctrl+c", "cancel query"),
+		),
+		ToggleOnError: key.NewBinding(
+			key.WithKeys("ctrl+o"),
+			key.WithHelp("ctrl+o", "toggle stop/continue on error"),
+		),
+		ManualCommit: key.NewBinding(
+			key.WithKeys("ctrl+x"),
+			key.WithHelp("ctrl+x", "toggle manual commit"),
+		),
+		Commit: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", "commit transaction"),
+		),
+		Rollback: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "rollback transaction"),
+		),
 	}
 }
 
@@ -44,25 +109,41 @@ func newEditorPaneKeymap() editorKeyMap {
 func NewEditorPane(width, height int, db drivers.Database) *EditorPaneModel {
 	ti := textarea.New()
 	ti.Placeholder = "Enter SQL Code Here..."
-	ti.CharLimit = 1000
+	ti.CharLimit = 0 // Scripts can be pasted without being cut off
+	ti.MaxHeight = 1000
 	ti.ShowLineNumbers = false
 	ti.Prompt = " "
 
 	pane := &EditorPaneModel{
-		width:    width,
-		height:   height,
-		textarea: ti,
-		err:      nil,
-		focused:  false,
-		db:       db,
-		keys:     newEditorPaneKeymap(),
+		width:       width,
+		height:      height,
+		textarea:    ti,
+		err:         nil,
+		focused:     false,
+		db:          db,
+		keys:        newEditorPaneKeymap(),
+		stopOnError: true,
+		paramValues: map[string]map[string]string{},
 	}
 
 	pane.updateStyles()
+	pane.updateKeys()
 
 	return pane
 }
 
+// Turns off the keys of features the connection's driver lacks, which also hides them
+// from the help
+func (m *EditorPaneModel) updateKeys() {
+	transactions := drivers.Supports(m.db, drivers.CapTransactions)
+	m.keys.ManualCommit.SetEnabled(transactions)
+	m.keys.Commit.SetEnabled(transactions)
+	m.keys.Rollback.SetEnabled(transactions)
+
+	_, explains := m.db.(drivers.Explainer)
+	m.keys.ExplainQuery.SetEnabled(m.db == nil || explains)
+}
+
 // Code for changing from active to inactive window
 func (m *EditorPaneModel) updateStyles() {
 	m.styles = lipgloss.NewStyle().
@@ -83,7 +164,227 @@ func (m *EditorPaneModel) Query() string {
 }
 
 func (m *EditorPaneModel) KeyMap() []key.Binding {
-	return []key.Binding{m.keys.ExecuteQuery}
+	return []key.Binding{m.keys.ExecuteQuery, m.keys.ExplainQuery, m.keys.CancelQuery, m.keys.ToggleOnError, m.keys.ManualCommit, m.keys.Commit, m.keys.Rollback}
+}
+
+// Points the editor at another connection, cancelling anything still running on the old one
+func (m *EditorPaneModel) SetDatabase(db drivers.Database) {
+	m.releaseQuery()
+	m.running = false
+	m.db = db
+	m.updateKeys()
+}
+
+// Shows the read-only badge while a read-only connection is open
+func (m *EditorPaneModel) SetReadOnly(readOnly bool) {
+	m.readOnly = readOnly
+}
+
+// Used in test for checking how scripts handle failing statements
+func (m *EditorPaneModel) StopOnError() bool {
+	return m.stopOnError
+}
+
+// Used by the layout for the footer indicator and to confirm quitting with uncommitted work
+func (m *EditorPaneModel) InTransaction() bool {
+	return m.db != nil && m.db.InTransaction()
+}
+
+// Used by the layout to keep keys in the editor while the parameter form is open
+func (m *EditorPaneModel) ParamFormOpen() bool {
+	return m.paramForm != nil
+}
+
+// Used in test for checking the commit mode
+func (m *EditorPaneModel) ManualCommit() bool {
+	return m.manualCommit
+}
+
+// Used in test for checking if a query is in flight
+func (m *EditorPaneModel) Running() bool {
+	return m.running
+}
+
+// Runs the buffer in the background with a context that can be cancelled.
+// Buffers with placeholders ask for their values first.
+func (m *EditorPaneModel) executeQuery(query string) tea.Cmd {
+	if m.db == nil || m.running {
+		return nil
+	}
+
+	if placeholders := drivers.Placeholders(query, drivers.DialectOf(m.db)); len(placeholders) > 0 {
+		m.paramForm = NewParamFormModel(query, placeholders, m.paramValues[strings.TrimSpace(query)])
+		return m.paramForm.Init()
+	}
+
+	return m.runQuery(query, nil)
+}
+
+// Runs a query with bind args for its placeholders, or without when args is nil.
+// Buffers with more than one statement are run as a script.
+func (m *EditorPaneModel) runQuery(query string, args map[string]any) tea.Cmd {
+	if m.db == nil || m.running {
+		return nil
+	}
+
+	// The previous result no longer needs its context
+	m.releaseQuery()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelQuery = cancel
+	m.running = true
+	m.queryID++
+
+	db := m.db
+	stopOnError := m.stopOnError
+	manualCommit := m.manualCommit
+	statements := drivers.SplitStatements(query, drivers.DialectOf(m.db))
+	started := QueryStartedMsg{ID: m.queryID, StartedAt: time.Now()}
+
+	return tea.Batch(
+		func() tea.Msg {
+			return started
+		},
+		func() tea.Msg {
+			// Manual commit mode opens a transaction with the first statement after a commit or rollback
+			if manualCommit && !db.InTransaction() {
+				if err := db.BeginTransaction(ctx); err != nil {
+					return drivers.QueryResultMsg{Error: err}
+				}
+			}
+
+			switch {
+			case len(statements) > 1:
+				return drivers.ExecuteScriptWithArgs(ctx, db, statements, args, stopOnError)
+			case args != nil:
+				return db.ExecuteQueryWithArgs(ctx, query, args)
+			default:
+				return db.ExecuteQuery(ctx, query)
+			}
+		},
+	)
+}
+
+// Shows the plan of the buffer instead of running it. Placeholders are asked for
+// first, like when running it.
+func (m *EditorPaneModel) explainQuery(query string) tea.Cmd {
+	if m.db == nil || m.running {
+		return nil
+	}
+
+	if _, ok := m.db.(drivers.Explainer); !ok {
+		return func() tea.Msg {
+			return msgtypes.NewErrMsg(errors.New("This database can't explain queries"))
+		}
+	}
+
+	switch len(drivers.SplitStatements(query, drivers.DialectOf(m.db))) {
+	case 0:
+		return nil
+	case 1:
+	default:
+		return func() tea.Msg {
+			return msgtypes.NewErrMsg(errors.New("Only a single statement can be explained"))
+		}
+	}
+
+	if placeholders := drivers.Placeholders(query, drivers.DialectOf(m.db)); len(placeholders) > 0 {
+		m.paramForm = NewParamFormModel(query, placeholders, m.paramValues[strings.TrimSpace(query)])
+		m.explainParams = true
+		return m.paramForm.Init()
+	}
+
+	return m.runExplain(query, nil)
+}
+
+// Asks the database for the plan of a query in the background
+func (m *EditorPaneModel) runExplain(query string, args map[string]any) tea.Cmd {
+	explainer, ok := m.db.(drivers.Explainer)
+	if !ok || m.running {
+		return nil
+	}
+
+	m.releaseQuery()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelQuery = cancel
+	m.running = true
+	m.queryID++
+
+	started := QueryStartedMsg{ID: m.queryID, StartedAt: time.Now()}
+
+	return tea.Batch(
+		func() tea.Msg {
+			return started
+		},
+		func() tea.Msg {
+			return explainer.ExplainQuery(ctx, query, args)
+		},
+	)
+}
+
+// Cancels the running query. The driver reports the cancellation through QueryResultMsg.
+func (m *EditorPaneModel) CancelQuery() {
+	if m.running && m.cancelQuery != nil {
+		m.cancelQuery()
+	}
+}
+
+// Switches between auto commit and manual commit. Leaving manual commit mode needs the
+// open transaction to be ended first so its work isn't committed or lost by accident.
+func (m *EditorPaneModel) toggleManualCommit() tea.Cmd {
+	var notification string
+
+	switch {
+	case m.manualCommit && m.InTransaction():
+		return func() tea.Msg {
+			return msgtypes.NewErrMsg(errors.New("Commit or roll back the open transaction before leaving manual commit mode"))
+		}
+	case m.manualCommit:
+		m.manualCommit = false
+		notification = "Auto commit: every statement is committed when it runs"
+	default:
+		m.manualCommit = true
+		notification = "Manual commit: statements run in a transaction until ctrl+s commits or ctrl+r rolls back"
+	}
+
+	return func() tea.Msg {
+		return msgtypes.NewNotificationMsg(notification)
+	}
+}
+
+// Commits or rolls back the open transaction in the background
+func (m *EditorPaneModel) endTransaction(commit bool) tea.Cmd {
+	if m.running {
+		return nil
+	}
+
+	if !m.InTransaction() {
+		return func() tea.Msg {
+			return msgtypes.NewNotificationMsg("No transaction is open")
+		}
+	}
+
+	// Queries wait until the transaction has ended
+	m.running = true
+	db := m.db
+
+	return func() tea.Msg {
+		var err error
+		if commit {
+			err = db.Commit()
+		} else {
+			err = db.Rollback()
+		}
+		return TransactionEndedMsg{Committed: commit, Err: err}
+	}
+}
+
+func (m *EditorPaneModel) releaseQuery() {
+	if m.cancelQuery != nil {
+		m.cancelQuery()
+		m.cancelQuery = nil
+	}
 }
 
 func (m *EditorPaneModel) Init() tea.Cmd {
@@ -105,13 +406,97 @@ func (m *EditorPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.textarea.SetValue(msg.Query)
 		return m, nil
 
+	case CancelQueryMsg:
+		m.CancelQuery()
+		return m, nil
+
+	case drivers.QueryResultMsg:
+		// Query finished. Keep the context alive while the result pane pages through an open cursor.
+		if msg.Cursor == nil {
+			m.releaseQuery()
+		}
+		m.running = false
+		return m, nil
+
+	case drivers.ScriptResultMsg, drivers.QueryPlanMsg:
+		m.releaseQuery()
+		m.running = false
+		return m, nil
+
+	case SubmitParamsMsg:
+		m.paramForm = nil
+		m.paramValues[strings.TrimSpace(msg.Query)] = msg.Values
+
+		args := make(map[string]any, len(msg.Values))
+		for placeholder, value := range msg.Values {
+			args[placeholder] = drivers.ParseParamValue(value)
+		}
+
+		if m.explainParams {
+			m.explainParams = false
+			return m, m.runExplain(msg.Query, args)
+		}
+		return m, m.runQuery(msg.Query, args)
+
+	case CancelParamsMsg:
+		m.paramForm = nil
+		m.explainParams = false
+		return m, nil
+
+	case TransactionEndedMsg:
+		m.running = false
+		m.releaseQuery()
+
+		if msg.Err != nil {
+			return m, func() tea.Msg {
+				return msgtypes.NewErrMsg(msg.Err)
+			}
+		}
+
+		notification := "Transaction rolled back"
+		if msg.Committed {
+			notification = "Transaction committed"
+		}
+		return m, func() tea.Msg {
+			return msgtypes.NewNotificationMsg(notification)
+		}
+
 	case tea.KeyMsg:
+		// The parameter form takes all keys while it is open
+		if m.paramForm != nil {
+			_, cmd = m.paramForm.Update(msg)
+			return m, cmd
+		}
+
 		switch {
 		case key.Matches(msg, m.keys.ExecuteQuery):
-			query := m.textarea.Value()
+			return m, m.executeQuery(m.textarea.Value())
+
+		case key.Matches(msg, m.keys.ExplainQuery):
+			return m, m.explainQuery(m.textarea.Value())
+
+		case key.Matches(msg, m.keys.CancelQuery):
+			m.CancelQuery()
+			return m, nil
+
+		case key.Matches(msg, m.keys.ToggleOnError):
+			m.stopOnError = !m.stopOnError
+			notification := "Scripts will continue after a failing statement"
+			if m.stopOnError {
+				notification = "Scripts will stop at the first failing statement"
+			}
 			return m, func() tea.Msg {
-				return m.db.ExecuteQuery(query)
+				return msgtypes.NewNotificationMsg(notification)
 			}
+
+		case key.Matches(msg, m.keys.ManualCommit):
+			return m, m.toggleManualCommit()
+
+		case key.Matches(msg, m.keys.Commit):
+			return m, m.endTransaction(true)
+
+		case key.Matches(msg, m.keys.Rollback):
+			return m, m.endTransaction(false)
 		}
 
 		switch msg.Type {
@@ -156,5 +541,9 @@ func (m EditorPaneModel) View() string {
 		paneStyle = m.styles
 	}
 
-	return paneStyle.Render(m.textarea.View())
+	if m.paramForm != nil {
+		return renderPane(paneStyle, m.paramForm.View(), m.readOnly)
+	}
+
+	return renderPane(paneStyle, m.textarea.View(), m.readOnly)
 }
@@ -1,10 +1,13 @@
 package panes
 
 import (
+	"fmt"
+
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/jdkingsbury/americano/internal/drivers"
 )
 
 // TODO: See if we can use the help bubble tea component to help with keymaps
@@ -14,6 +17,14 @@ import (
 var (
 	keyBindingStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(pine)).Padding(0, 1).Bold(true)
 	helpTextStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color(foam)).Padding(0, 1).Bold(true)
+
+	connectionStyles = map[drivers.ConnectionState]lipgloss.Style{
+		drivers.Connecting:       lipgloss.NewStyle().Foreground(lipgloss.Color(gold)).Padding(0, 1).Bold(true),
+		drivers.Connected:        lipgloss.NewStyle().Foreground(lipgloss.Color(pine)).Padding(0, 1).Bold(true),
+		drivers.ConnectionFailed: lipgloss.NewStyle().Foreground(lipgloss.Color(love)).Padding(0, 1).Bold(true),
+	}
+	transactionStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(base)).Background(lipgloss.Color(gold)).Padding(0, 1).Bold(true)
+	promptStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color(gold)).Padding(0, 1).Bold(true)
 )
 
 type KeyMap struct {
@@ -28,6 +39,12 @@ type FooterModel struct {
 	width        int
 	height       int
 	showFullHelp bool
+
+	connectionName  string
+	connectionState drivers.ConnectionState
+	transactionOpen bool
+	// Question shown in place of the key bindings while it waits for an answer
+	prompt string
 }
 type SetKeyMapMsg struct {
 	FullHelpKeys  [][]key.Binding
@@ -65,6 +82,29 @@ func (m *FooterModel) SetKeyBindings(fullHelpKeys [][]key.Binding, shortHelpKeys
 	}
 }
 
+// Shows which connection the editor and tree are using
+func (m *FooterModel) SetConnectionState(name string, state drivers.ConnectionState) {
+	m.connectionName = name
+	m.connectionState = state
+}
+
+func (m *FooterModel) SetTransactionOpen(open bool) {
+	m.transactionOpen = open
+}
+
+func (m *FooterModel) SetPrompt(prompt string) {
+	m.prompt = prompt
+}
+
+func (m *FooterModel) connectionView() string {
+	style, ok := connectionStyles[m.connectionState]
+	if !ok {
+		return ""
+	}
+
+	return style.Render(fmt.Sprintf("[%s: %s]", m.connectionName, m.connectionState))
+}
+
 func (m *FooterModel) Init() tea.Cmd {
 	return nil
 }
@@ -84,6 +124,17 @@ func (m *FooterModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m *FooterModel) View() string {
 	var helpView []string
 
+	if connection := m.connectionView(); connection != "" {
+		helpView = append(helpView, connection)
+	}
+	if m.transactionOpen {
+		helpView = append(helpView, transactionStyle.Render("TX OPEN"))
+	}
+
+	if m.prompt != "" {
+		return lipgloss.JoinHorizontal(lipgloss.Top, append(helpView, promptStyle.Render(m.prompt))...)
+	}
+
 	if m.showFullHelp {
 		for _, section := range m.keyMap.FullHelp() {
 			for idx, kb := range section {
@@ -1,9 +1,13 @@
 package panes
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/jdkingsbury/americano/internal/config"
 	"github.com/jdkingsbury/americano/internal/drivers"
 	"github.com/jdkingsbury/americano/msgtypes"
 )
@@ -21,9 +25,21 @@ type LayoutModel struct {
 	currentPane pane
 	panes       []tea.Model
 	footer      *FooterModel
+	session     *drivers.SessionManager
 	width       int
 	height      int
 	keys        layoutKeyMap
+
+	// Runs once the user confirms leaving an open transaction behind
+	pendingConfirm func() tea.Cmd
+
+	// Secrets file for ${secret:name} references, unlocked once per session
+	secretsPath string
+	secrets     *config.Secrets
+	// Open while asking for the master passphrase, nil otherwise
+	passphraseForm *PassphraseFormModel
+	// Runs once the secrets are unlocked
+	pendingUnlock func() tea.Cmd
 }
 
 type layoutKeyMap struct {
@@ -31,6 +47,8 @@ type layoutKeyMap struct {
 	PrevPane key.Binding
 	Help     key.Binding
 	Quit     key.Binding
+	Confirm  key.Binding
+	Deny     key.Binding
 }
 
 func newLayoutPaneKeyMapModel() layoutKeyMap {
@@ -51,6 +69,14 @@ func newLayoutPaneKeyMapModel() layoutKeyMap {
 			key.WithKeys("Q"),
 			key.WithHelp("Q", "quit americano"),
 		),
+		Confirm: key.NewBinding(
+			key.WithKeys("y", "Y"),
+			key.WithHelp("y", "confirm"),
+		),
+		Deny: key.NewBinding(
+			key.WithKeys("n", "N", "esc"),
+			key.WithHelp("n", "cancel"),
+		),
 	}
 }
 
@@ -67,10 +93,11 @@ func NewLayoutModel() *LayoutModel {
 			editorPane,  // Index 1
 			resultPane,  // Index 2
 		},
-		footer: footerPane,
-		width:  0,
-		height: 0,
-		keys:   newLayoutPaneKeyMapModel(),
+		footer:  footerPane,
+		session: drivers.NewSessionManager(),
+		width:   0,
+		height:  0,
+		keys:    newLayoutPaneKeyMapModel(),
 	}
 
 	// Set the initial active pane
@@ -101,6 +128,16 @@ func (m *LayoutModel) updatePaneSizes() {
 	m.footer.width = m.width
 }
 
+// Limits the memory used by rows fetched into the result pane
+func (m *LayoutModel) SetResultMemoryCap(bytes int64) {
+	m.panes[ResultPane].(*ResultPaneModel).SetMemoryCap(bytes)
+}
+
+// Loads the connections saved in the config file at path and saves later changes to it
+func (m *LayoutModel) LoadConnections(path string) error {
+	return m.panes[SideBarPane].(*SideBarPaneModel).LoadConnections(path)
+}
+
 // Used for checking the current pane in test
 func (m *LayoutModel) CurrentPane() pane {
 	return m.currentPane
@@ -113,51 +150,264 @@ func (m *LayoutModel) Panes() []tea.Model {
 
 // Used in test for checking the layout width
 func (m *LayoutModel) Width() int {
-  return m.width
+	return m.width
 }
 
 // Used in test for checking the layout height
 func (m *LayoutModel) Height() int {
-  return m.height
+	return m.height
+}
+
+// Sets the secrets file that ${secret:name} references in connection URLs are read from
+func (m *LayoutModel) SetSecretsPath(path string) {
+	m.secretsPath = path
+}
+
+// Used in test for checking if the passphrase is being asked for
+func (m *LayoutModel) PassphraseFormOpen() bool {
+	return m.passphraseForm != nil
+}
+
+// Reports the outcome of opening the secrets file with the passphrase
+type SecretsUnlockedMsg struct {
+	Secrets *config.Secrets
+	Err     error
 }
 
-func setupEditorPaneForDBConnection(dbURL string, width, height int) (*EditorPaneModel, tea.Cmd) {
-	db, notificationMsg := drivers.ConnectToDatabase(dbURL)
-	if db == nil {
-		return nil, func() tea.Msg {
-			return notificationMsg
+// Runs action once the secrets are unlocked, asking for the passphrase the first time
+func (m *LayoutModel) withSecrets(action func() tea.Cmd) tea.Cmd {
+	if m.secrets != nil {
+		return action()
+	}
+
+	if m.secretsPath == "" {
+		return func() tea.Msg {
+			return msgtypes.NewErrMsg(errors.New("No secrets file is set"))
 		}
 	}
 
-	// Initialize the editor pane with connected database
-	editorPane := NewEditorPane(width, height, db)
-	return editorPane, func() tea.Msg {
-		return notificationMsg
+	m.pendingUnlock = action
+	m.passphraseForm = NewPassphraseFormModel(!config.SecretsExist(m.secretsPath))
+	return m.passphraseForm.Init()
+}
+
+// Derives the key in the background, it takes a moment on purpose
+func (m *LayoutModel) unlockSecrets(passphrase string) tea.Cmd {
+	path := m.secretsPath
+	return func() tea.Msg {
+		secrets, err := config.OpenSecrets(path, passphrase)
+		return SecretsUnlockedMsg{Secrets: secrets, Err: err}
+	}
+}
+
+func (m *LayoutModel) applySecretsUnlocked(msg SecretsUnlockedMsg) tea.Cmd {
+	// The passphrase form was cancelled meanwhile
+	if m.passphraseForm == nil {
+		return nil
+	}
+
+	if msg.Err != nil {
+		m.passphraseForm.SetError(fmt.Errorf("Failed to unlock secrets: %w", msg.Err))
+		return nil
 	}
+
+	m.secrets = msg.Secrets
+	drivers.SetSecretLookup(m.secrets.Get)
+
+	action := m.pendingUnlock
+	m.passphraseForm = nil
+	m.pendingUnlock = nil
+	return tea.Batch(m.pruneSecrets(), action())
 }
 
-func setupDBTreeForDBConnection(dbURL string) (*DBTreeModel, tea.Cmd) {
-	db, notificationMsg := drivers.ConnectToDatabase(dbURL)
-	if db == nil {
-		return nil, func() tea.Msg {
-			return notificationMsg
+// Stores the passwords of a submitted connection, then lets the sidebar save it
+func (m *LayoutModel) storeSecrets(msg SubmitFormMsg) tea.Cmd {
+	for name, value := range msg.Secrets {
+		if err := m.secrets.Set(name, value); err != nil {
+			return func() tea.Msg {
+				return msgtypes.NewErrMsg(fmt.Errorf("Failed to save secret %s: %w", name, err))
+			}
 		}
 	}
 
-	// Initialize the db tree with connected database
-	dbTree := NewDBTreeModel(db)
-	return dbTree, func() tea.Msg {
-		return notificationMsg
+	msg.Secrets = nil
+	_, cmd := m.panes[SideBarPane].(*SideBarPaneModel).Update(msg)
+	return tea.Batch(cmd, m.pruneSecrets())
+}
+
+// Deletes the secrets no saved connection references anymore, e.g. the password of
+// a deleted connection. Runs again once the secrets are unlocked when they are locked.
+func (m *LayoutModel) pruneSecrets() tea.Cmd {
+	if m.secrets == nil {
+		return nil
+	}
+
+	referenced := map[string]bool{}
+	for _, connection := range m.panes[SideBarPane].(*SideBarPaneModel).Connections() {
+		for _, name := range drivers.SecretNames(connection.URL) {
+			referenced[name] = true
+		}
+	}
+
+	for _, name := range m.secrets.Names() {
+		if referenced[name] {
+			continue
+		}
+		if err := m.secrets.Delete(name); err != nil {
+			return func() tea.Msg {
+				return msgtypes.NewErrMsg(fmt.Errorf("Failed to delete secret %s: %w", name, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reports the outcome of connecting the session with the given id
+type ConnectionStateMsg struct {
+	ID    int
+	Name  string
+	State drivers.ConnectionState
+	DB    drivers.Database
+	// The profile only allows statements that read data
+	ReadOnly bool
+	// Notification or error from the driver
+	Msg tea.Msg
+}
+
+// Asks the user to confirm before Msg is sent, e.g. before deleting a connection
+type ConfirmMsg struct {
+	Prompt string
+	Msg    tea.Msg
+}
+
+// Closes the previous connection and connects to the selected one in the background
+func (m *LayoutModel) openSession(name, url string, limits drivers.Limits) tea.Cmd {
+	// Nothing may keep using the old connection once it is closed
+	m.panes[EditorPane].(*EditorPaneModel).SetDatabase(nil)
+	m.panes[SideBarPane].(*SideBarPaneModel).dbTreeModel = NewDBTreeModel(nil)
+
+	id := m.session.Open(name)
+	m.footer.SetConnectionState(name, drivers.Connecting)
+	m.setReadOnly(false)
+
+	session := m.session
+	return func() tea.Msg {
+		db, msg, current := session.Connect(id, url, limits)
+		if !current {
+			return nil
+		}
+
+		state := drivers.Connected
+		if db == nil {
+			state = drivers.ConnectionFailed
+		}
+
+		return ConnectionStateMsg{ID: id, Name: name, State: state, DB: db, ReadOnly: limits.ReadOnly, Msg: msg}
+	}
+}
+
+// Shares the connected database with the tree and the editor
+func (m *LayoutModel) applyConnectionState(msg ConnectionStateMsg) tea.Cmd {
+	// A newer session replaced this one
+	if msg.ID != m.session.ID() {
+		return nil
+	}
+
+	m.footer.SetConnectionState(msg.Name, msg.State)
+
+	var loadTree tea.Cmd
+	if msg.State == drivers.Connected {
+		sideBarPane := m.panes[SideBarPane].(*SideBarPaneModel)
+		sideBarPane.dbTreeModel = NewDBTreeModel(msg.DB)
+		sideBarPane.currentView = DBTreeView
+		loadTree = sideBarPane.dbTreeModel.Init()
+
+		m.panes[EditorPane].(*EditorPaneModel).SetDatabase(msg.DB)
+		m.setReadOnly(msg.ReadOnly)
 	}
+
+	return tea.Batch(loadTree, func() tea.Msg {
+		return msg.Msg
+	})
+}
+
+// Shows or hides the read-only badge on every pane
+func (m *LayoutModel) setReadOnly(readOnly bool) {
+	m.panes[SideBarPane].(*SideBarPaneModel).SetReadOnly(readOnly)
+	m.panes[EditorPane].(*EditorPaneModel).SetReadOnly(readOnly)
+	m.panes[ResultPane].(*ResultPaneModel).SetReadOnly(readOnly)
+}
+
+// Closes the open connection. Called on quit.
+func (m *LayoutModel) Close() error {
+	return m.session.Close()
+}
+
+// Used in test for checking the shared connection
+func (m *LayoutModel) Session() *drivers.SessionManager {
+	return m.session
 }
 
 func (m *LayoutModel) Init() tea.Cmd {
 	return m.setActivePane(true)
 }
 
+// Asks before running action while a transaction is open, as it would roll the transaction back
+func (m *LayoutModel) confirmIfInTransaction(prompt string, action func() tea.Cmd) tea.Cmd {
+	if !m.panes[EditorPane].(*EditorPaneModel).InTransaction() {
+		return action()
+	}
+
+	m.pendingConfirm = action
+	m.footer.SetPrompt(prompt + " The open transaction will be rolled back. (y/n)")
+	return nil
+}
+
+func (m *LayoutModel) handleConfirmKey(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		action := m.pendingConfirm
+		m.pendingConfirm = nil
+		m.footer.SetPrompt("")
+		return action()
+
+	case key.Matches(msg, m.keys.Deny):
+		m.pendingConfirm = nil
+		m.footer.SetPrompt("")
+	}
+
+	return nil
+}
+
+// Used in test for checking if the layout is waiting for a confirmation
+func (m *LayoutModel) ConfirmPending() bool {
+	return m.pendingConfirm != nil
+}
+
 func (m *LayoutModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+
+	// The footer shows when statements are running in an uncommitted transaction
+	m.footer.SetTransactionOpen(m.panes[EditorPane].(*EditorPaneModel).InTransaction())
+
+	return model, cmd
+}
+
+func (m *LayoutModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
+	// Other input waits until the pending confirmation is answered
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && m.pendingConfirm != nil {
+		return m, m.handleConfirmKey(keyMsg)
+	}
+
+	// Keys go to the passphrase form while it is open
+	if _, ok := msg.(tea.KeyMsg); ok && m.passphraseForm != nil {
+		_, cmd = m.passphraseForm.Update(msg)
+		return m, cmd
+	}
+
 	switch msg := msg.(type) {
 
 	case InsertQueryMsg:
@@ -166,24 +416,78 @@ func (m *LayoutModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.panes[EditorPane], cmd = editorPane.Update(msg)
 		return m, cmd
 
-	case SetupDBTreeMsg:
-		dbTree, setupCmd := setupDBTreeForDBConnection(msg.dbURL)
-
-		if dbTree != nil {
-			sideBarPane := m.panes[SideBarPane].(*SideBarPaneModel)
-			sideBarPane.dbTreeModel = dbTree
-			sideBarPane.currentView = DBTreeView
+	case ConnectMsg:
+		return m, m.confirmIfInTransaction(fmt.Sprintf("Switch to %s?", msg.Name), func() tea.Cmd {
+			if drivers.HasSecretReferences(msg.URL) {
+				return m.withSecrets(func() tea.Cmd {
+					return m.openSession(msg.Name, msg.URL, msg.Limits)
+				})
+			}
+			return m.openSession(msg.Name, msg.URL, msg.Limits)
+		})
+
+	case SubmitFormMsg:
+		if len(msg.Secrets) > 0 {
+			return m, m.withSecrets(func() tea.Cmd {
+				return m.storeSecrets(msg)
+			})
 		}
 
-		return m, setupCmd
+		// An edit may have replaced the secret the connection used
+		sideBarPane := m.panes[SideBarPane].(*SideBarPaneModel)
+		_, cmd = sideBarPane.Update(msg)
+		return m, tea.Batch(cmd, m.pruneSecrets())
+
+	case DeleteConnectionMsg:
+		sideBarPane := m.panes[SideBarPane].(*SideBarPaneModel)
+		_, cmd = sideBarPane.Update(msg)
+		return m, tea.Batch(cmd, m.pruneSecrets())
+
+	case SubmitPassphraseMsg:
+		return m, m.unlockSecrets(msg.Passphrase)
+
+	case CancelPassphraseMsg:
+		m.passphraseForm = nil
+		m.pendingUnlock = nil
+		return m, nil
+
+	case SecretsUnlockedMsg:
+		return m, m.applySecretsUnlocked(msg)
 
-	case SetupEditorPaneMsg:
-		editorPane, setupCmd := setupEditorPaneForDBConnection(msg.dbURL, m.width, m.height)
-		if editorPane != nil {
-			m.panes[EditorPane] = editorPane
+	case ConnectionStateMsg:
+		return m, m.applyConnectionState(msg)
+
+	case ConfirmMsg:
+		confirmed := msg.Msg
+		m.pendingConfirm = func() tea.Cmd {
+			return func() tea.Msg {
+				return confirmed
+			}
 		}
+		m.footer.SetPrompt(msg.Prompt + " (y/n)")
+		return m, nil
+
+	case QueryStartedMsg, queryTickMsg, RowsFetchedMsg:
+		// Running indicator and fetched rows go to the result pane regardless of focus
+		resultPane := m.panes[ResultPane].(*ResultPaneModel)
+		_, cmd = resultPane.Update(msg)
+		return m, cmd
+
+	case CancelQueryMsg:
+		editorPane := m.panes[EditorPane].(*EditorPaneModel)
+		editorPane.Update(msg)
+		return m, nil
+
+	case SchemaChangedMsg, TreeLoadedMsg, TableSchemaLoadedMsg, ConnectionTestedMsg:
+		// Attaching and loading schemas finish in the background, the tree updates wherever the focus is
+		sideBarPane := m.panes[SideBarPane].(*SideBarPaneModel)
+		_, cmd = sideBarPane.Update(msg)
+		return m, cmd
 
-		return m, setupCmd
+	case TransactionEndedMsg, SubmitParamsMsg, CancelParamsMsg:
+		editorPane := m.panes[EditorPane].(*EditorPaneModel)
+		_, cmd = editorPane.Update(msg)
+		return m, cmd
 
 	case SetKeyMapMsg:
 		m.footer.SetKeyBindings(msg.FullHelpKeys, msg.ShortHelpKeys)
@@ -197,9 +501,18 @@ func (m *LayoutModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		resultPane := m.panes[ResultPane].(*ResultPaneModel)
 		resultPane.Update(msg)
 
-	case drivers.QueryResultMsg:
+	case drivers.QueryResultMsg, drivers.ScriptResultMsg, drivers.QueryPlanMsg:
+		// Let the editor know the query finished
+		editorPane := m.panes[EditorPane].(*EditorPaneModel)
+		editorPane.Update(msg)
 		m.currentPane = ResultPane
 
+		// The connection the query ran on may have dropped an attachment
+		sideBarPane := m.panes[SideBarPane].(*SideBarPaneModel)
+		resultPane := m.panes[ResultPane].(*ResultPaneModel)
+		_, cmd = resultPane.Update(msg)
+		return m, tea.Batch(cmd, sideBarPane.dbTreeModel.checkDroppedAttachments())
+
 	// Fetch Window Size
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -211,13 +524,13 @@ func (m *LayoutModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Check if Adding Connection to disable layout commands temporarily
 		if m.currentPane == SideBarPane {
 			sideBarPane := m.panes[SideBarPane].(*SideBarPaneModel)
-			if sideBarPane.showInputForm {
+			if sideBarPane.FormOpen() {
 				break
 			}
 			// Check if using the editor pane
 		} else if m.currentPane == EditorPane {
 			editorPane := m.panes[EditorPane].(*EditorPaneModel)
-			if editorPane.focused {
+			if editorPane.focused || editorPane.ParamFormOpen() {
 				break
 			}
 		}
@@ -239,7 +552,10 @@ func (m *LayoutModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case key.Matches(msg, m.keys.Quit):
-			return m, tea.Quit
+			return m, m.confirmIfInTransaction("Quit americano?", func() tea.Cmd {
+				m.Close()
+				return tea.Quit
+			})
 		}
 	}
 
@@ -265,7 +581,7 @@ func (m *LayoutModel) setActivePane(isActive bool) tea.Cmd {
 			return func() tea.Msg {
 				return SetKeyMapMsg{
 					FullHelpKeys:  append(layoutFullHelp, pane.KeyMap()),
-					ShortHelpKeys: append(pane.KeyMap()),
+					ShortHelpKeys: pane.KeyMap(),
 				}
 			}
 		}
@@ -275,7 +591,7 @@ func (m *LayoutModel) setActivePane(isActive bool) tea.Cmd {
 			return func() tea.Msg {
 				return SetKeyMapMsg{
 					FullHelpKeys:  append(layoutFullHelp, pane.KeyMap()),
-					ShortHelpKeys: append(pane.KeyMap()),
+					ShortHelpKeys: pane.KeyMap(),
 				}
 			}
 		}
@@ -285,7 +601,7 @@ func (m *LayoutModel) setActivePane(isActive bool) tea.Cmd {
 			return func() tea.Msg {
 				return SetKeyMapMsg{
 					FullHelpKeys:  append(layoutFullHelp, pane.KeyMap()),
-					ShortHelpKeys: append(pane.KeyMap()),
+					ShortHelpKeys: pane.KeyMap(),
 				}
 			}
 		}
@@ -306,6 +622,12 @@ func (m *LayoutModel) View() string {
 	layout := lipgloss.JoinHorizontal(lipgloss.Left, leftSide, rightSide)
 	layout = lipgloss.JoinVertical(lipgloss.Top, layout, resultView)
 
+	// The passphrase form takes the place of the panes until it is answered
+	if m.passphraseForm != nil {
+		dialog := passphraseDialogStyle.Render(m.passphraseForm.View())
+		layout = lipgloss.Place(lipgloss.Width(layout), lipgloss.Height(layout), lipgloss.Center, lipgloss.Center, dialog)
+	}
+
 	footerView := m.footer.View()
 
 	return lipgloss.JoinVertical(lipgloss.Top, layout, footerView)
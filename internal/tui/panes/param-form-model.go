@@ -0,0 +1,139 @@
+package panes
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+/* Prompts for the values of a query's placeholders */
+
+type CancelParamsMsg struct{}
+
+// Values typed for each placeholder of Query, keyed like drivers.Placeholders
+type SubmitParamsMsg struct {
+	Query  string
+	Values map[string]string
+}
+
+type ParamFormModel struct {
+	query        string
+	placeholders []string
+	focusIndex   int
+	inputs       []textinput.Model
+	keys         dbFormKeyMap
+}
+
+// Previous values are filled in so a query can be rerun with small changes
+func NewParamFormModel(query string, placeholders []string, previous map[string]string) *ParamFormModel {
+	m := ParamFormModel{
+		query:        query,
+		placeholders: placeholders,
+		inputs:       make([]textinput.Model, len(placeholders)),
+		keys:         newDBFormKeyMap(),
+	}
+
+	for i, placeholder := range placeholders {
+		ti := textinput.New()
+		ti.CharLimit = 0
+		ti.Width = 30
+		ti.Prompt = fmt.Sprintf("%s: ", placeholder)
+		ti.Placeholder = "value"
+		ti.SetValue(previous[placeholder])
+
+		if i == 0 {
+			ti.Focus()
+		}
+
+		m.inputs[i] = ti
+	}
+
+	return &m
+}
+
+func (m *ParamFormModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *ParamFormModel) values() map[string]string {
+	values := make(map[string]string, len(m.inputs))
+	for i, placeholder := range m.placeholders {
+		values[placeholder] = m.inputs[i].Value()
+	}
+	return values
+}
+
+func (m *ParamFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.CancelForm):
+			return m, func() tea.Msg {
+				return CancelParamsMsg{}
+			}
+
+		case key.Matches(msg, m.keys.NextInput):
+			m.focusIndex = (m.focusIndex + 1) % (len(m.inputs) + 1)
+
+		case key.Matches(msg, m.keys.PrevInput):
+			m.focusIndex = (m.focusIndex - 1 + len(m.inputs) + 1) % (len(m.inputs) + 1)
+
+		case key.Matches(msg, m.keys.SubmitForm):
+			// Enter on the last input submits as well, so a single value is quick to give
+			if m.focusIndex >= len(m.inputs)-1 {
+				submit := SubmitParamsMsg{Query: m.query, Values: m.values()}
+				return m, func() tea.Msg {
+					return submit
+				}
+			}
+			m.focusIndex++
+		}
+
+		// Update focus for inputs
+		for i := range m.inputs {
+			if i == m.focusIndex {
+				m.inputs[i].Focus()
+			} else {
+				m.inputs[i].Blur()
+			}
+		}
+	}
+
+	// Update all inputs
+	for i := range m.inputs {
+		var cmd tea.Cmd
+		m.inputs[i], cmd = m.inputs[i].Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+func (m *ParamFormModel) View() string {
+	var output string
+
+	output += formTitleStyle.Render("Query Parameters") + "\n"
+	output += formHintStyle.Render("  NULL binds null, 'quoted' values bind as text") + "\n\n"
+
+	// Input fields
+	for i := range m.inputs {
+		if i == m.focusIndex {
+			output += formFocusedStyle.Render(m.inputs[i].View()) + "\n"
+		} else {
+			output += formBlurredStyle.Render(m.inputs[i].View()) + "\n"
+		}
+	}
+
+	// Button field
+	if m.focusIndex == len(m.inputs) {
+		output += formSubmitStyle.Render("\n[ Run ]\n")
+	} else {
+		output += formBlurredSubmit.Render("\nRun\n")
+	}
+
+	return output
+}
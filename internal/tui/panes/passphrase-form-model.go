@@ -0,0 +1,173 @@
+package panes
+
+import (
+	"errors"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+/* Asks for the master passphrase that unlocks the secrets file */
+
+var passphraseDialogStyle = lipgloss.NewStyle().
+	Border(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color(gold)).
+	Padding(1, 2)
+
+type CancelPassphraseMsg struct{}
+
+type SubmitPassphraseMsg struct {
+	Passphrase string
+}
+
+// Order of the passphrase form inputs. Confirm is only shown when the passphrase
+// is chosen for a new secrets file.
+const (
+	passphraseInput = iota
+	confirmPassphraseInput
+)
+
+type PassphraseFormModel struct {
+	focusIndex int
+	inputs     []textinput.Model
+	keys       dbFormKeyMap
+	// A new secrets file is created with the passphrase
+	create bool
+	// Set while the passphrase is checked
+	unlocking bool
+	err       error
+}
+
+func NewPassphraseFormModel(create bool) *PassphraseFormModel {
+	m := PassphraseFormModel{
+		keys:   newDBFormKeyMap(),
+		create: create,
+	}
+
+	count := 1
+	if create {
+		count = 2
+	}
+	m.inputs = make([]textinput.Model, count)
+
+	for i := range m.inputs {
+		ti := textinput.New()
+		ti.CharLimit = 0
+		ti.Width = 30
+		ti.EchoMode = textinput.EchoPassword
+		ti.EchoCharacter = '•'
+
+		switch i {
+		case passphraseInput:
+			ti.Placeholder = "Master Passphrase"
+			ti.Focus()
+		case confirmPassphraseInput:
+			ti.Placeholder = "Repeat Passphrase"
+		}
+
+		m.inputs[i] = ti
+	}
+
+	return &m
+}
+
+// Shows why the passphrase didn't unlock the secrets and asks again
+func (m *PassphraseFormModel) SetError(err error) {
+	m.unlocking = false
+	m.err = err
+	m.inputs[passphraseInput].SetValue("")
+}
+
+func (m *PassphraseFormModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *PassphraseFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.CancelForm):
+			return m, func() tea.Msg {
+				return CancelPassphraseMsg{}
+			}
+
+		case key.Matches(msg, m.keys.NextInput):
+			m.focusIndex = (m.focusIndex + 1) % len(m.inputs)
+
+		case key.Matches(msg, m.keys.PrevInput):
+			m.focusIndex = (m.focusIndex - 1 + len(m.inputs)) % len(m.inputs)
+
+		case key.Matches(msg, m.keys.SubmitForm):
+			if m.unlocking {
+				return m, nil
+			}
+
+			passphrase := m.inputs[passphraseInput].Value()
+			switch {
+			case passphrase == "":
+				m.err = errors.New("Enter the master passphrase")
+				return m, nil
+			case m.create && passphrase != m.inputs[confirmPassphraseInput].Value():
+				m.err = errors.New("The passphrases don't match")
+				return m, nil
+			}
+
+			m.err = nil
+			m.unlocking = true
+			return m, func() tea.Msg {
+				return SubmitPassphraseMsg{Passphrase: passphrase}
+			}
+		}
+
+		// Update focus for inputs
+		for i := range m.inputs {
+			if i == m.focusIndex {
+				m.inputs[i].Focus()
+			} else {
+				m.inputs[i].Blur()
+			}
+		}
+	}
+
+	// Update all inputs
+	for i := range m.inputs {
+		var cmd tea.Cmd
+		m.inputs[i], cmd = m.inputs[i].Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+func (m *PassphraseFormModel) View() string {
+	var output string
+
+	if m.create {
+		output += formTitleStyle.Render("Create Master Passphrase") + "\n"
+		output += formHintStyle.Padding(0, 1).Render("Encrypts the passwords of saved connections") + "\n\n"
+	} else {
+		output += formTitleStyle.Render("Unlock Secrets") + "\n\n"
+	}
+
+	// Input fields
+	for i := range m.inputs {
+		if i == m.focusIndex {
+			output += formFocusedStyle.Render(m.inputs[i].View()) + "\n"
+		} else {
+			output += formBlurredStyle.Render(m.inputs[i].View()) + "\n"
+		}
+	}
+
+	switch {
+	case m.unlocking:
+		output += formHintStyle.Padding(0, 1).Render("Unlocking...") + "\n"
+	case m.err != nil:
+		output += formErrorStyle.Render(m.err.Error()) + "\n"
+	}
+
+	return output
+}
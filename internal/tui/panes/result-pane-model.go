@@ -1,17 +1,49 @@
 package panes
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jdkingsbury/americano/internal/drivers"
 	"github.com/jdkingsbury/americano/msgtypes"
+	"github.com/mattn/go-runewidth"
 )
 
 // NOTE: May need to change how we update the width and height of the table
 type ClearNotificationMsg struct{}
 
+// Refreshes the elapsed time while a query is running
+type queryTickMsg struct {
+	ID int
+}
+
+const queryTickInterval = 100 * time.Millisecond
+
+// Shown for NULL values so they can't be mistaken for the string "NULL"
+const nullDisplay = "<null>"
+
+const (
+	// Default limit on the estimated memory used by fetched rows
+	DefaultResultMemoryCap = 64 << 20
+	fetchPageSize          = 200
+	// Rows from the end of the table at which the next page is fetched
+	fetchThreshold = 20
+)
+
+// Next page of rows read from an open cursor
+type RowsFetchedMsg struct {
+	Cursor    *drivers.RowCursor
+	Cells     [][]drivers.Cell
+	HasMore   bool
+	Truncated bool
+	Err       error
+}
+
 type ResultPaneModel struct {
 	styles       lipgloss.Style
 	activeStyles lipgloss.Style
@@ -21,11 +53,32 @@ type ResultPaneModel struct {
 	isActive     bool
 	table        table.Model
 	notification string
+	summary      string
 	keys         resultKeyMaps
+	running      bool
+	queryID      int
+	startedAt    time.Time
+	cells        [][]drivers.Cell         // Typed values of the table, kept for formatting on resize
+	results      []drivers.QueryResultMsg // Per statement results of the last script
+	resultIndex  int
+	scriptTotal  int
+	cursor       *drivers.RowCursor // Open cursor of the shown result, nil once exhausted
+	hasMore      bool
+	fetching     bool
+	fetchErr     error
+	memoryCap    int64
+	usedBytes    int64
+	capped       bool
+	truncated    bool               // The connection's row limit cut off the shown result
+	plan         []drivers.PlanNode // Steps of the explained query, nil unless a plan is shown
+	readOnly     bool               // Shows the read-only badge
 }
 
 type resultKeyMaps struct {
-	Focus key.Binding
+	Focus       key.Binding
+	CancelQuery key.Binding
+	NextResult  key.Binding
+	PrevResult  key.Binding
 }
 
 func newResultKeyMaps() resultKeyMaps {
@@ -34,11 +87,29 @@ func newResultKeyMaps() resultKeyMaps {
 			key.WithKeys("esc"),
 			key.WithHelp("esc", "toggle table focus"),
 		),
+		CancelQuery: key.NewBinding(
+			key.WithKeys("ctrl+c"),
+			key.WithHelp("ctrl+c", "cancel query"),
+		),
+		NextResult: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "next statement result"),
+		),
+		PrevResult: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "previous statement result"),
+		),
 	}
 }
 
 func (m ResultPaneModel) KeyMap() []key.Binding {
-	return []key.Binding{m.keys.Focus}
+	return []key.Binding{m.keys.Focus, m.keys.CancelQuery, m.keys.NextResult, m.keys.PrevResult}
+}
+
+func queryTick(id int) tea.Cmd {
+	return tea.Tick(queryTickInterval, func(time.Time) tea.Msg {
+		return queryTickMsg{ID: id}
+	})
 }
 
 // Initialize Result Pane
@@ -67,11 +138,12 @@ func NewResultPaneModel(width, height int) *ResultPaneModel {
 	t.SetStyles(s)
 
 	pane := &ResultPaneModel{
-		width:  width,
-		height: height,
-		err:    nil,
-		table:  t,
-		keys:   newResultKeyMaps(),
+		width:     width,
+		height:    height,
+		err:       nil,
+		table:     t,
+		keys:      newResultKeyMaps(),
+		memoryCap: DefaultResultMemoryCap,
 	}
 
 	pane.updateStyles()
@@ -83,11 +155,28 @@ func NewResultPaneModel(width, height int) *ResultPaneModel {
 func (m *ResultPaneModel) HandleMsg(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case drivers.QueryResultMsg:
+		m.closeCursor()
+		m.plan = nil
 		if msg.Error != nil {
 			m.err = msg.Error
 			return m, nil
 		}
-		m.UpdateTable(msg.Columns, msg.Rows)
+		m.showQueryResult(msg)
+		return m, nil
+
+	case drivers.ScriptResultMsg:
+		m.closeCursor()
+		m.plan = nil
+		m.showScriptResult(msg)
+		return m, nil
+
+	case drivers.QueryPlanMsg:
+		m.closeCursor()
+		m.showQueryPlan(msg)
+		return m, nil
+
+	case RowsFetchedMsg:
+		m.appendFetchedRows(msg)
 		return m, nil
 
 	case msgtypes.NotificationMsg:
@@ -108,12 +197,316 @@ func (m *ResultPaneModel) Table() table.Model {
 	return m.table
 }
 
+// Used for testing the summary of statements that do not return rows
+func (m *ResultPaneModel) Summary() string {
+	return m.summary
+}
+
+// Used for testing which statement result of a script is shown
+func (m *ResultPaneModel) ResultIndex() int {
+	return m.resultIndex
+}
+
+// Limits the estimated memory used by rows fetched from a cursor
+func (m *ResultPaneModel) SetMemoryCap(bytes int64) {
+	m.memoryCap = bytes
+}
+
+// Used for testing the steps of the shown query plan
+func (m *ResultPaneModel) Plan() []drivers.PlanNode {
+	return m.plan
+}
+
+// Shows the read-only badge while a read-only connection is open
+func (m *ResultPaneModel) SetReadOnly(readOnly bool) {
+	m.readOnly = readOnly
+}
+
+// Used for testing whether more rows can be fetched for the shown result
+func (m *ResultPaneModel) HasMoreRows() bool {
+	return m.hasMore
+}
+
+func (m *ResultPaneModel) closeCursor() {
+	if m.cursor != nil {
+		m.cursor.Close()
+		m.cursor = nil
+	}
+	m.fetching = false
+}
+
+// Fetches the next page once the selected row gets close to the last fetched row
+func (m *ResultPaneModel) fetchMoreRows() tea.Cmd {
+	if m.cursor == nil || m.fetching || m.table.Cursor() < len(m.table.Rows())-fetchThreshold {
+		return nil
+	}
+
+	m.fetching = true
+	cursor := m.cursor
+
+	return func() tea.Msg {
+		_, cells, hasMore, err := cursor.Fetch(fetchPageSize)
+		return RowsFetchedMsg{Cursor: cursor, Cells: cells, HasMore: hasMore, Truncated: cursor.Truncated(), Err: err}
+	}
+}
+
+func (m *ResultPaneModel) appendFetchedRows(msg RowsFetchedMsg) {
+	// Ignore pages of a cursor that was replaced by a newer result
+	if msg.Cursor != m.cursor {
+		return
+	}
+
+	m.fetching = false
+	m.fetchErr = msg.Err
+	m.hasMore = msg.HasMore
+	m.truncated = msg.Truncated
+
+	if len(msg.Cells) > 0 {
+		start := len(m.cells)
+		m.cells = append(m.cells, msg.Cells...)
+		m.usedBytes += estimateCellBytes(msg.Cells)
+
+		// Only format the new rows, the existing ones keep their formatting
+		tableColumns := m.table.Columns()
+		tableRows := m.table.Rows()
+		for _, cellRow := range m.cells[start:] {
+			tableRows = append(tableRows, formatCellRow(cellRow, tableColumns))
+		}
+		m.table.SetRows(tableRows)
+	}
+
+	if !msg.HasMore || msg.Err != nil {
+		m.cursor = nil
+		return
+	}
+
+	if m.usedBytes >= m.memoryCap {
+		m.closeCursor()
+		m.capped = true
+	}
+}
+
+// Rough size of fetched values, used for the memory cap
+func estimateCellBytes(cells [][]drivers.Cell) int64 {
+	var size int64
+	for _, cellRow := range cells {
+		for _, cell := range cellRow {
+			size += int64(len(cell.String())) + 32
+		}
+	}
+
+	return size
+}
+
+// Status line telling how many rows were fetched and whether more are available
+func (m *ResultPaneModel) fetchStatus() string {
+	if m.truncated {
+		return fmt.Sprintf("showing the first %d rows, the connection's row limit cut off the rest", len(m.table.Rows()))
+	}
+
+	if !m.hasMore && m.fetchErr == nil {
+		return ""
+	}
+
+	status := fmt.Sprintf("fetched %d rows", len(m.table.Rows()))
+	switch {
+	case m.fetchErr != nil:
+		status += fmt.Sprintf(", failed to fetch more: %v", m.fetchErr)
+	case m.fetching:
+		status += ", fetching more..."
+	case m.capped:
+		status += fmt.Sprintf(", more available but stopped at the %d MB memory cap", m.memoryCap>>20)
+	case m.cursor != nil:
+		status += ", more available (scroll down to fetch)"
+	default:
+		status += ", more available"
+	}
+
+	return status
+}
+
+// Keeps every statement result of a script and shows the first failure, or the last result
+func (m *ResultPaneModel) showScriptResult(msg drivers.ScriptResultMsg) {
+	m.results = msg.Results
+	m.scriptTotal = msg.Total
+	m.notification = ""
+
+	if len(m.results) == 0 {
+		return
+	}
+
+	index := len(m.results) - 1
+	for i, result := range m.results {
+		if result.Error != nil {
+			index = i
+			break
+		}
+	}
+
+	m.showResult(index)
+}
+
+// Shows the result of a single statement of the last script
+func (m *ResultPaneModel) showResult(index int) {
+	m.resultIndex = index
+	result := m.results[index]
+
+	if result.Error != nil {
+		m.err = result.Error
+		m.summary = ""
+		return
+	}
+
+	m.err = nil
+	m.showQueryResult(result)
+}
+
+// Header naming the statement whose result is shown when a script was run
+func (m *ResultPaneModel) scriptHeader() string {
+	if len(m.results) == 0 || (len(m.results) < 2 && m.scriptTotal < 2) {
+		return ""
+	}
+
+	query := strings.Join(strings.Fields(m.results[m.resultIndex].Query), " ")
+	if maxWidth := m.width - 30; maxWidth > 0 {
+		query = runewidth.Truncate(query, maxWidth, "…")
+	}
+
+	header := fmt.Sprintf("Statement %d/%d: %s", m.resultIndex+1, len(m.results), query)
+	if notRun := m.scriptTotal - len(m.results); notRun > 0 {
+		header += fmt.Sprintf(" (stopped on error, %d not run)", notRun)
+	}
+
+	return header
+}
+
+// Shows a summary line for exec statements and the result grid for everything else
+func (m *ResultPaneModel) showQueryResult(msg drivers.QueryResultMsg) {
+	if msg.IsExec {
+		m.summary = execSummary(msg)
+		return
+	}
+
+	m.summary = ""
+	m.cursor = msg.Cursor
+	m.hasMore = msg.HasMore
+	m.fetchErr = nil
+	m.capped = false
+	m.truncated = msg.Truncated
+	m.usedBytes = estimateCellBytes(msg.Cells)
+
+	if msg.Cells != nil {
+		m.UpdateTypedTable(msg.Columns, msg.Cells)
+		return
+	}
+	m.UpdateTable(msg.Columns, msg.Rows)
+}
+
+// Replaces the shown result with the plan of an explained query
+func (m *ResultPaneModel) showQueryPlan(msg drivers.QueryPlanMsg) {
+	m.results = nil
+	m.scriptTotal = 0
+	m.summary = ""
+	m.plan = nil
+
+	if msg.Error != nil {
+		m.err = msg.Error
+		return
+	}
+
+	m.err = nil
+	m.plan = msg.Nodes
+	if m.plan == nil {
+		m.plan = []drivers.PlanNode{}
+	}
+}
+
+// Renders the plan as a tree with the steps that have warnings highlighted, e.g.
+//
+//	QUERY PLAN (1 warning)
+//	├─ ⚠ SCAN users (full table scan)
+//	└─ SEARCH orders USING INDEX orders_user (user_id=?)
+func (m *ResultPaneModel) planView() string {
+	warningStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(love))
+	branchStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(muted))
+
+	header := "QUERY PLAN"
+	switch warnings := drivers.PlanWarnings(m.plan); {
+	case len(m.plan) == 0:
+		header += " (no steps)"
+	case warnings == 1:
+		header += " (1 warning)"
+	case warnings > 1:
+		header += fmt.Sprintf(" (%d warnings)", warnings)
+	}
+	lines := []string{lipgloss.NewStyle().Foreground(lipgloss.Color(gold)).Render(header)}
+
+	ids := map[int]bool{}
+	for _, node := range m.plan {
+		ids[node.ID] = true
+	}
+
+	// Steps whose parent isn't part of the plan are shown at the top
+	children := map[int][]drivers.PlanNode{}
+	for _, node := range m.plan {
+		parent := node.Parent
+		if !ids[parent] || parent == node.ID {
+			parent = 0
+		}
+		children[parent] = append(children[parent], node)
+	}
+
+	var walk func(parent int, indent string)
+	walk = func(parent int, indent string) {
+		steps := children[parent]
+		for i, node := range steps {
+			branch, nextIndent := "├─ ", "│  "
+			if i == len(steps)-1 {
+				branch, nextIndent = "└─ ", "   "
+			}
+
+			detail := node.Detail
+			if node.Warning != "" {
+				detail = warningStyle.Render(fmt.Sprintf("⚠ %s (%s)", detail, node.Warning))
+			}
+			lines = append(lines, branchStyle.Render(indent+branch)+detail)
+
+			walk(node.ID, indent+nextIndent)
+		}
+	}
+	walk(0, "")
+
+	// Long plans are cut to the height of the pane
+	if maxLines := (m.height / 3) - 1; maxLines > 1 && len(lines) > maxLines {
+		hidden := len(lines) - maxLines + 1
+		lines = append(lines[:maxLines-1], branchStyle.Render(fmt.Sprintf("… %d more steps", hidden)))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func execSummary(msg drivers.QueryResultMsg) string {
+	rowWord := "rows"
+	if msg.RowsAffected == 1 {
+		rowWord = "row"
+	}
+
+	summary := fmt.Sprintf("Query OK, %d %s affected", msg.RowsAffected, rowWord)
+	if msg.LastInsertId != 0 {
+		summary += fmt.Sprintf(", last insert id %d", msg.LastInsertId)
+	}
+
+	return summary
+}
+
 func (m *ResultPaneModel) UpdateTable(columns []string, rowData [][]string) {
 	if len(columns) == 0 {
 		msgtypes.NewNotificationMsg("No columns to display")
 		return
 	}
 
+	m.cells = nil
+
 	// Constants for border, padding, and column spacing
 	borderWidth := 6
 	padding := 6
@@ -150,6 +543,57 @@ func (m *ResultPaneModel) UpdateTable(columns []string, rowData [][]string) {
 	m.table.SetRows(tableRows)
 }
 
+// Updates the table from typed cells. Numbers are right aligned, NULLs, bytes and times
+// are shown in their own format.
+func (m *ResultPaneModel) UpdateTypedTable(columns []string, cells [][]drivers.Cell) {
+	if len(columns) == 0 {
+		msgtypes.NewNotificationMsg("No columns to display")
+		return
+	}
+
+	m.UpdateTable(columns, nil)
+	m.cells = cells
+	m.formatTypedRows()
+}
+
+// Formats the typed cells to the current column widths
+func (m *ResultPaneModel) formatTypedRows() {
+	tableColumns := m.table.Columns()
+
+	tableRows := make([]table.Row, 0, len(m.cells))
+	for _, cellRow := range m.cells {
+		tableRows = append(tableRows, formatCellRow(cellRow, tableColumns))
+	}
+
+	m.table.SetRows(tableRows)
+}
+
+func formatCellRow(cellRow []drivers.Cell, tableColumns []table.Column) table.Row {
+	row := make(table.Row, len(cellRow))
+	for i, cell := range cellRow {
+		width := 0
+		if i < len(tableColumns) {
+			width = tableColumns[i].Width
+		}
+		row[i] = formatCell(cell, width)
+	}
+
+	return row
+}
+
+func formatCell(cell drivers.Cell, width int) string {
+	switch cell.Kind {
+	case drivers.NullCell:
+		return nullDisplay
+	case drivers.NumberCell:
+		// Right align numbers within the column
+		return fmt.Sprintf("%*s", width, cell.String())
+	default:
+		// Keep multi-line text on a single table row
+		return strings.ReplaceAll(cell.String(), "\n", "↵")
+	}
+}
+
 // Styles for result pane
 func (m *ResultPaneModel) updateStyles() {
 	m.styles = lipgloss.NewStyle().
@@ -174,7 +618,27 @@ func (m *ResultPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 
+	case QueryStartedMsg:
+		m.running = true
+		m.queryID = msg.ID
+		m.startedAt = msg.StartedAt
+		m.notification = ""
+		m.err = nil
+		return m, queryTick(msg.ID)
+
+	case queryTickMsg:
+		// Keep ticking until the query with this id finishes
+		if m.running && msg.ID == m.queryID {
+			return m, queryTick(msg.ID)
+		}
+		return m, nil
+
 	case drivers.QueryResultMsg:
+		m.running = false
+		m.results = nil
+		m.scriptTotal = 0
+		m.plan = nil
+		m.closeCursor()
 		cmds = append(cmds, func() tea.Msg {
 			return ClearNotificationMsg{}
 		})
@@ -184,7 +648,22 @@ func (m *ResultPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		m.UpdateTable(msg.Columns, msg.Rows)
+		m.showQueryResult(msg)
+
+	case drivers.ScriptResultMsg:
+		m.running = false
+		m.plan = nil
+		m.closeCursor()
+		m.showScriptResult(msg)
+
+	case drivers.QueryPlanMsg:
+		m.running = false
+		m.closeCursor()
+		m.showQueryPlan(msg)
+
+	case RowsFetchedMsg:
+		m.appendFetchedRows(msg)
+		return m, nil
 
 	case msgtypes.NotificationMsg:
 		cmds = append(cmds, func() tea.Msg {
@@ -216,15 +695,37 @@ func (m *ResultPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Calculate column widths
 		availableWidth := m.width - 16 // Account for borders and padding
 		columns := m.table.Columns()   // Get the existing columns
-		columnWidth := availableWidth / len(columns)
+		if len(columns) > 0 {
+			columnWidth := availableWidth / len(columns)
 
-		for i := range columns {
-			columns[i].Width = columnWidth
+			for i := range columns {
+				columns[i].Width = columnWidth
+			}
+			m.table.SetColumns(columns)
+			if m.cells != nil {
+				m.formatTypedRows()
+			}
 		}
-		m.table.SetColumns(columns)
 
 	case tea.KeyMsg:
 		switch {
+		case key.Matches(msg, m.keys.CancelQuery):
+			if m.running {
+				return m, func() tea.Msg {
+					return CancelQueryMsg{}
+				}
+			}
+
+		case key.Matches(msg, m.keys.NextResult):
+			if len(m.results) > 1 {
+				m.showResult((m.resultIndex + 1) % len(m.results))
+			}
+
+		case key.Matches(msg, m.keys.PrevResult):
+			if len(m.results) > 1 {
+				m.showResult((m.resultIndex - 1 + len(m.results)) % len(m.results))
+			}
+
 		case key.Matches(msg, m.keys.Focus):
 			if m.table.Focused() {
 				m.table.Blur()
@@ -237,6 +738,11 @@ func (m *ResultPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.table, tableCmd = m.table.Update(msg)
 	cmds = append(cmds, tableCmd)
 
+	// Page in more rows as the selection approaches the end of the table
+	if _, ok := msg.(tea.KeyMsg); ok {
+		cmds = append(cmds, m.fetchMoreRows())
+	}
+
 	return m, tea.Batch(cmds...)
 }
 
@@ -249,28 +755,61 @@ func (m *ResultPaneModel) View() string {
 		paneStyle = m.styles
 	}
 
-	if m.err != nil {
-		// For displaying errors
-		return paneStyle.Render(lipgloss.NewStyle().
-			Foreground(lipgloss.Color(rose)).
-			Render(m.err.Error()),
+	if m.running {
+		// For displaying the running query indicator
+		elapsed := time.Since(m.startedAt).Truncate(100 * time.Millisecond)
+		return renderPane(paneStyle, lipgloss.NewStyle().
+			Foreground(lipgloss.Color(gold)).
+			Render(fmt.Sprintf("Running query... %s (%s to cancel)", elapsed, m.keys.CancelQuery.Help().Key)),
+			m.readOnly,
 		)
 	}
 
 	// For displaying notifications
 	if m.notification != "" {
-		return paneStyle.Render(
+		return renderPane(paneStyle,
 			lipgloss.NewStyle().
 				Foreground(lipgloss.Color(text)).
 				Render(m.notification),
+			m.readOnly,
 		)
 	}
 
-	tableView := m.table.View()
+	var content string
+	if m.err != nil {
+		// For displaying errors
+		content = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(rose)).
+			Render(m.err.Error())
+	} else if m.plan != nil {
+		// For displaying the plan of an explained query
+		content = m.planView()
+	} else if m.summary != "" {
+		// For displaying the summary of statements that do not return rows
+		content = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(foam)).
+			Render(m.summary)
+	} else {
+		content = lipgloss.NewStyle().
+			Padding(0, 2).
+			Render(m.table.View())
+
+		// For displaying how many rows were fetched from a large result
+		if status := m.fetchStatus(); status != "" {
+			content = lipgloss.JoinVertical(lipgloss.Left,
+				content,
+				lipgloss.NewStyle().Foreground(lipgloss.Color(gold)).Render(status),
+			)
+		}
+	}
 
-	centeredTable := lipgloss.NewStyle().
-		Padding(0, 2).
-		Render(tableView)
+	// For displaying which statement of a script the result belongs to
+	if header := m.scriptHeader(); header != "" {
+		content = lipgloss.JoinVertical(lipgloss.Left,
+			lipgloss.NewStyle().Foreground(lipgloss.Color(gold)).Render(header),
+			content,
+		)
+	}
 
-	return paneStyle.Render(centeredTable)
+	return renderPane(paneStyle, content, m.readOnly)
 }
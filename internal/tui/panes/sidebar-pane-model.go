@@ -26,11 +26,18 @@ type SideBarPaneModel struct {
 	dbFormModel   *DBFormModel
 	showInputForm bool
 	keys          sideBarKeyMap
+
+	// Open while choosing a database file to attach, nil otherwise
+	attachForm *AttachFormModel
+	// Shows the read-only badge
+	readOnly bool
 }
 
 type sideBarKeyMap struct {
 	SwitchView key.Binding
 	Select     key.Binding
+	Attach     key.Binding
+	Detach     key.Binding
 }
 
 func newSideBarKeyMap() sideBarKeyMap {
@@ -43,11 +50,19 @@ func newSideBarKeyMap() sideBarKeyMap {
 			key.WithKeys("enter"),
 			key.WithHelp("enter", "select item"),
 		),
+		Attach: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "attach database"),
+		),
+		Detach: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "detach selected schema"),
+		),
 	}
 }
 
 func (m SideBarPaneModel) KeyMap() []key.Binding {
-	return []key.Binding{m.keys.SwitchView, m.keys.Select}
+	return append([]key.Binding{m.keys.SwitchView, m.keys.Select, m.keys.Attach, m.keys.Detach}, m.dbConnModel.KeyMap()...)
 }
 
 func NewSideBarPane(width, height int) *SideBarPaneModel {
@@ -80,6 +95,26 @@ func (m *SideBarPaneModel) ShowInputForm() bool {
 	return m.showInputForm
 }
 
+// Shows the read-only badge while a read-only connection is open
+func (m *SideBarPaneModel) SetReadOnly(readOnly bool) {
+	m.readOnly = readOnly
+}
+
+// Loads the saved connections into the connections list
+func (m *SideBarPaneModel) LoadConnections(path string) error {
+	return m.dbConnModel.LoadConnections(path)
+}
+
+// Used for testing the saved connections
+func (m *SideBarPaneModel) Connections() []DBConnItems {
+	return m.dbConnModel.Connections()
+}
+
+// Whether a form is taking the key input
+func (m *SideBarPaneModel) FormOpen() bool {
+	return m.showInputForm || m.attachForm != nil
+}
+
 func (m *SideBarPaneModel) updateStyles() {
 	m.styles = lipgloss.NewStyle().
 		Width((m.width / 3) - 10).
@@ -101,6 +136,40 @@ func (m *SideBarPaneModel) Init() tea.Cmd {
 func (m *SideBarPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
+	switch msg := msg.(type) {
+	case SubmitAttachMsg:
+		m.attachForm = nil
+		return m, m.dbTreeModel.attach(msg.Path, msg.Schema)
+
+	case CancelAttachMsg:
+		m.attachForm = nil
+		return m, nil
+
+	case SchemaChangedMsg, TreeLoadedMsg, TableSchemaLoadedMsg:
+		_, cmd = m.dbTreeModel.Update(msg)
+		return m, cmd
+
+	case DeleteConnectionMsg:
+		_, cmd = m.dbConnModel.Update(msg)
+		return m, cmd
+
+	case ConnectionTestedMsg:
+		_, cmd = m.dbFormModel.Update(msg)
+		return m, cmd
+
+	case EditConnectionMsg:
+		m.dbFormModel.Edit(msg.Index, msg.Item)
+		m.showInputForm = true
+		m.currentView = ConnectionsView
+		return m, m.dbFormModel.Init()
+	}
+
+	// Keys go to the attach form while it is open
+	if m.attachForm != nil {
+		_, cmd = m.attachForm.Update(msg)
+		return m, cmd
+	}
+
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -113,6 +182,17 @@ func (m *SideBarPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.currentView == ConnectionsView && m.dbConnModel.FocusedOnButton() {
 				m.showInputForm = true
 			}
+		case key.Matches(msg, m.keys.Attach) && !m.showInputForm:
+			if m.currentView == DBTreeView && m.dbTreeModel.CanAttach() {
+				m.attachForm = NewAttachFormModel()
+				return m, m.attachForm.Init()
+			}
+
+		case key.Matches(msg, m.keys.Detach) && !m.showInputForm:
+			if m.currentView == DBTreeView {
+				return m, m.dbTreeModel.detachSelected()
+			}
+
 		case key.Matches(msg, m.keys.SwitchView):
 			if m.currentView == ConnectionsView {
 				m.currentView = DBTreeView
@@ -130,8 +210,13 @@ func (m *SideBarPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.currentView = ConnectionsView
 
 	case SubmitFormMsg:
-		// Add New Connection
-		m.dbConnModel.AddConnection(msg.Name, msg.URL)
+		// Add New Connection, or replace the one that was edited
+		if msg.Edit {
+			m.dbConnModel.UpdateConnection(msg.Index, msg.Name, msg.URL, msg.Limits)
+		} else {
+			m.dbConnModel.AddConnection(msg.Name, msg.URL, msg.Limits)
+		}
+		cmd = m.dbConnModel.saveConnections()
 		// Hide form after submission
 		m.showInputForm = false
 		// Reset Form
@@ -161,7 +246,9 @@ func (m *SideBarPaneModel) View() string {
 	var content string
 
 	// Connection Views
-	if m.showInputForm {
+	if m.attachForm != nil {
+		content = m.attachForm.View()
+	} else if m.showInputForm {
 		content = m.dbFormModel.View()
 	} else if m.currentView == ConnectionsView {
 		content = m.dbConnModel.View()
@@ -176,6 +263,6 @@ func (m *SideBarPaneModel) View() string {
 		paneStyle = m.styles
 	}
 
-	sideBarPane := paneStyle.Render(content)
+	sideBarPane := renderPane(paneStyle, content, m.readOnly)
 	return sideBarPane
 }
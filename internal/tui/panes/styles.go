@@ -1,6 +1,8 @@
 package panes
 
 import (
+	"strings"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -33,3 +35,29 @@ var (
 	highlightMed  = lipgloss.Color("#dfdad9")
 	highlightHigh = lipgloss.Color("#cecacd")
 )
+
+// Shown in the top border of every pane while a read-only connection is open
+const readOnlyBadge = " RO "
+
+var readOnlyBadgeStyle = lipgloss.NewStyle().Foreground(base).Background(gold).Bold(true)
+
+// Renders a pane, with the read-only badge set into its top border when readOnly is true
+func renderPane(style lipgloss.Style, content string, readOnly bool) string {
+	if !readOnly {
+		return style.Render(content)
+	}
+
+	body := style.BorderTop(false).Render(content)
+	border := style.GetBorderStyle()
+	fill := lipgloss.Width(body) - lipgloss.Width(border.TopLeft+border.Top+readOnlyBadge+border.TopRight)
+	if fill < 0 {
+		return style.Render(content)
+	}
+
+	borderStyle := lipgloss.NewStyle().Foreground(style.GetBorderTopForeground())
+	top := borderStyle.Render(border.TopLeft+border.Top) +
+		readOnlyBadgeStyle.Render(readOnlyBadge) +
+		borderStyle.Render(strings.Repeat(border.Top, fill)+border.TopRight)
+
+	return lipgloss.JoinVertical(lipgloss.Left, top, body)
+}
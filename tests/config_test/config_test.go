@@ -0,0 +1,83 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jdkingsbury/americano/internal/config"
+	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_PathUsesXDGConfigHome(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	path, err := config.Path()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "americano", "config.json"), path)
+
+	// Relative paths are ignored
+	t.Setenv("XDG_CONFIG_HOME", "relative")
+	t.Setenv("HOME", dir)
+	path, err = config.Path()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, ".config", "americano", "config.json"), path)
+}
+
+func TestConfig_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "americano", "config.json")
+
+	// A missing file is an empty config
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, config.Version, cfg.Version)
+	assert.Empty(t, cfg.Connections)
+
+	limits := drivers.Limits{StatementTimeout: 30 * time.Second, MaxRows: 1000, ReadOnly: true}
+	cfg.Connections = []config.Connection{
+		config.NewConnection("dev", "sqlite://./dev.db", drivers.Limits{}),
+		config.NewConnection("prod snapshot", "postgres://localhost/app", limits),
+	}
+	require.NoError(t, config.Save(path, cfg))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"version": 1`)
+	assert.Contains(t, string(data), `"statement_timeout": "30s"`)
+
+	loaded, err := config.Load(path)
+	require.NoError(t, err)
+	require.Len(t, loaded.Connections, 2)
+	assert.Equal(t, "dev", loaded.Connections[0].Name)
+	assert.Equal(t, drivers.Limits{}, loaded.Connections[0].Limits())
+	assert.Equal(t, limits, loaded.Connections[1].Limits())
+
+	// Saving again replaces the file without leaving temp files behind
+	require.NoError(t, config.Save(path, config.Config{}))
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "config.json", entries[0].Name())
+}
+
+func TestConfig_RejectsNewerVersionsAndInvalidFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": 99, "connections": []}`), 0o600))
+	_, err := config.Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "version 99")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"connections": [{"name": "x", "statement_timeout": "soon"}]}`), 0o600))
+	_, err = config.Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid duration "soon"`)
+}
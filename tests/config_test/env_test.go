@@ -0,0 +1,42 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdkingsbury/americano/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte(`
+# Database settings
+export AMERICANO_TEST_HOST=db.internal
+AMERICANO_TEST_PASSWORD="p@ss \"word\""
+AMERICANO_TEST_RAW='$not#a comment'
+AMERICANO_TEST_PORT=5432 # default port
+AMERICANO_TEST_SET=from-file
+`), 0o600))
+
+	t.Setenv("AMERICANO_TEST_SET", "from-env")
+	for _, name := range []string{"AMERICANO_TEST_HOST", "AMERICANO_TEST_PASSWORD", "AMERICANO_TEST_RAW", "AMERICANO_TEST_PORT"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	require.NoError(t, config.LoadEnvFile(path))
+	assert.Equal(t, "db.internal", os.Getenv("AMERICANO_TEST_HOST"))
+	assert.Equal(t, `p@ss "word"`, os.Getenv("AMERICANO_TEST_PASSWORD"))
+	assert.Equal(t, "$not#a comment", os.Getenv("AMERICANO_TEST_RAW"))
+	assert.Equal(t, "5432", os.Getenv("AMERICANO_TEST_PORT"))
+	// The environment wins over the file
+	assert.Equal(t, "from-env", os.Getenv("AMERICANO_TEST_SET"))
+
+	// A missing file is fine, a broken one is not
+	assert.NoError(t, config.LoadEnvFile(filepath.Join(t.TempDir(), ".env")))
+	require.NoError(t, os.WriteFile(path, []byte("not a variable\n"), 0o600))
+	assert.ErrorContains(t, config.LoadEnvFile(path), "invalid line 1")
+}
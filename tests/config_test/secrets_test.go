@@ -0,0 +1,67 @@
+package config_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdkingsbury/americano/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecrets_EncryptedUnderPassphrase(t *testing.T) {
+	path := config.SecretsPath(filepath.Join(t.TempDir(), "americano", "config.json"))
+	assert.False(t, config.SecretsExist(path))
+
+	secrets, err := config.OpenSecrets(path, "correct horse")
+	require.NoError(t, err)
+	require.NoError(t, secrets.Set("orders", "p@ss/word"))
+	require.NoError(t, secrets.Set("reports", "hunter2"))
+	require.NoError(t, secrets.Delete("reports"))
+	assert.True(t, config.SecretsExist(path))
+
+	// Nothing readable is written to disk
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "p@ss/word")
+	assert.NotContains(t, string(data), "orders")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	reopened, err := config.OpenSecrets(path, "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders"}, reopened.Names())
+	value, ok := reopened.Get("orders")
+	assert.True(t, ok)
+	assert.Equal(t, "p@ss/word", value)
+
+	_, err = config.OpenSecrets(path, "wrong horse")
+	assert.ErrorIs(t, err, config.ErrWrongPassphrase)
+}
+
+func TestSecrets_RefusesNewerFileVersion(t *testing.T) {
+	path := config.SecretsPath(filepath.Join(t.TempDir(), "americano", "config.json"))
+
+	secrets, err := config.OpenSecrets(path, "correct horse")
+	require.NoError(t, err)
+	require.NoError(t, secrets.Set("orders", "hunter2"))
+
+	var file map[string]any
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &file))
+	assert.EqualValues(t, config.SecretsVersion, file["version"])
+
+	file["version"] = config.SecretsVersion + 1
+	data, err = json.Marshal(file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	_, err = config.OpenSecrets(path, "correct horse")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "reads up to version")
+}
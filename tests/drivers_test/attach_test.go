@@ -0,0 +1,122 @@
+package drivers_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/jdkingsbury/americano/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newArchiveFile(t *testing.T) string {
+	return tests.NewSQLiteFile(t, "archive.db",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, total REAL);",
+		"CREATE INDEX idx_orders_total ON orders (total);",
+		"CREATE VIEW big_orders AS SELECT * FROM orders WHERE total > 100;",
+		"INSERT INTO orders VALUES (1, 250), (2, 10);",
+	)
+}
+
+func TestSQLite_AttachListsTablesBySchema(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+	require.Nil(t, db.ExecuteQuery(ctx, "CREATE TABLE orders (id INTEGER PRIMARY KEY);").Error)
+
+	require.NoError(t, db.Attach(ctx, newArchiveFile(t), ""))
+	assert.Equal(t, "archive", db.Attachments()[0].Schema)
+
+	tables, err := db.GetTables(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []drivers.TableName{{Name: "orders"}, {Schema: "archive", Name: "orders"}}, tables)
+
+	columns, err := db.GetColumns(ctx, drivers.TableName{Schema: "archive", Name: "orders"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id"}, drivers.PrimaryKey(columns))
+
+	indexes, err := db.GetIndexes(ctx, drivers.TableName{Schema: "archive", Name: "orders"})
+	require.NoError(t, err)
+	require.Len(t, indexes, 1)
+	assert.Equal(t, []string{"total"}, indexes[0].Columns)
+
+	views, err := db.GetViews(ctx)
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	assert.Equal(t, drivers.TableName{Schema: "archive", Name: "big_orders"}, views[0].TableName)
+}
+
+func TestSQLite_AttachAppliesToEveryPooledConnection(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+
+	require.NoError(t, db.Attach(ctx, newArchiveFile(t), "old"))
+
+	// ATTACH is per connection, both of these must see the attached database
+	conn1, err := db.Connection.Conn(ctx)
+	require.NoError(t, err)
+	defer conn1.Close()
+	conn2, err := db.Connection.Conn(ctx)
+	require.NoError(t, err)
+	defer conn2.Close()
+
+	var count int
+	require.NoError(t, conn1.QueryRowContext(ctx, "SELECT count(*) FROM old.orders;").Scan(&count))
+	assert.Equal(t, 2, count)
+	require.NoError(t, conn2.QueryRowContext(ctx, "SELECT count(*) FROM old.orders;").Scan(&count))
+	conn1.Close()
+	conn2.Close()
+
+	require.NoError(t, db.Detach(ctx, "old"))
+
+	tables, err := db.GetTables(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tables)
+
+	result := db.ExecuteQuery(ctx, "SELECT count(*) FROM old.orders;")
+	assert.Error(t, result.Error)
+}
+
+func TestSQLite_DropsAttachmentsThatFailToAttach(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+	archive := newArchiveFile(t)
+	require.NoError(t, db.Attach(ctx, archive, ""))
+
+	// A directory in place of the file can't be attached by the next connection
+	require.NoError(t, os.Remove(archive))
+	require.NoError(t, os.Mkdir(archive, 0o755))
+
+	conn1, err := db.Connection.Conn(ctx)
+	require.NoError(t, err)
+	defer conn1.Close()
+	conn2, err := db.Connection.Conn(ctx)
+	require.NoError(t, err, "the connection is kept without the attachment")
+	defer conn2.Close()
+
+	assert.Empty(t, db.Attachments())
+	err = db.DroppedAttachments()
+	assert.ErrorContains(t, err, "Detached archive")
+	assert.NoError(t, db.DroppedAttachments(), "each drop is reported once")
+
+	var count int
+	require.NoError(t, conn2.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master;").Scan(&count))
+}
+
+func TestSQLite_AttachErrors(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+	archive := newArchiveFile(t)
+
+	assert.ErrorContains(t, db.Attach(ctx, archive+".missing", ""), "does not exist")
+	assert.ErrorContains(t, db.Attach(ctx, archive, "main"), "reserved")
+
+	require.NoError(t, db.Attach(ctx, archive, ""))
+	assert.ErrorContains(t, db.Attach(ctx, archive, "archive"), "already attached")
+	assert.ErrorContains(t, db.Detach(ctx, "nope"), "No database is attached")
+
+	require.NoError(t, db.BeginTransaction(ctx))
+	assert.ErrorContains(t, db.Detach(ctx, "archive"), "transaction")
+	require.NoError(t, db.Rollback())
+}
@@ -0,0 +1,145 @@
+package drivers_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Writes the files into a temp dir and opens it as a csv connection
+func newCSVDatabase(t *testing.T, files map[string]string) (*drivers.CSV, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	db := &drivers.CSV{}
+	require.NoError(t, db.Connect(context.Background(), "csv://"+dir))
+	t.Cleanup(func() { db.CloseConnection() })
+
+	return db, dir
+}
+
+func TestCSV_LoadsEachFileAsTable(t *testing.T) {
+	db, dir := newCSVDatabase(t, map[string]string{
+		"orders.csv":     "id,customer,total,zip\n1,\"Smith, Jane\",19.5,02134\n2,Bob,7,90210\n",
+		"line items.tsv": "order_id\tsku\n1\tA-1\n1\tB-2\n",
+		"notes.txt":      "not loaded",
+	})
+	ctx := context.Background()
+
+	tables, err := db.GetTables(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []drivers.TableName{{Name: "orders"}, {Name: "line_items"}}, tables)
+
+	name, err := db.GetDatabaseName(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Base(dir), name)
+
+	columns, err := db.GetColumns(ctx, drivers.TableName{Name: "orders"})
+	require.NoError(t, err)
+	require.Len(t, columns, 4)
+	assert.Equal(t, "INTEGER", columns[0].Type)
+	assert.Equal(t, "TEXT", columns[1].Type)
+	assert.Equal(t, "REAL", columns[2].Type)
+	assert.Equal(t, "TEXT", columns[3].Type, "leading zeros keep zip codes as text")
+
+	result := db.ExecuteQuery(ctx, "SELECT customer, total, zip FROM orders ORDER BY id;")
+	require.Nil(t, result.Error)
+	assert.Equal(t, [][]string{{"Smith, Jane", "19.5", "02134"}, {"Bob", "7", "90210"}}, result.Rows)
+
+	result = db.ExecuteQuery(ctx, "SELECT count(*) FROM line_items WHERE order_id = 1;")
+	require.Nil(t, result.Error)
+	assert.Equal(t, [][]string{{"2"}}, result.Rows)
+}
+
+func TestCSV_StreamsRowsPastTheTypeSample(t *testing.T) {
+	var content strings.Builder
+	content.WriteString("id,code\n")
+	for i := 1; i <= 2500; i++ {
+		fmt.Fprintf(&content, "%d,%d\n", i, i)
+	}
+	// Types come from the first rows, later values that don't fit are kept as they are
+	content.WriteString("2501,n/a\n")
+
+	db, _ := newCSVDatabase(t, map[string]string{"codes.csv": content.String()})
+	ctx := context.Background()
+
+	columns, err := db.GetColumns(ctx, drivers.TableName{Name: "codes"})
+	require.NoError(t, err)
+	assert.Equal(t, "INTEGER", columns[1].Type)
+
+	result := db.ExecuteQuery(ctx, "SELECT count(*), max(id) FROM codes;")
+	require.Nil(t, result.Error)
+	assert.Equal(t, [][]string{{"2501", "2501"}}, result.Rows)
+
+	result = db.ExecuteQuery(ctx, "SELECT code FROM codes WHERE id = 2501;")
+	require.Nil(t, result.Error)
+	assert.Equal(t, [][]string{{"n/a"}}, result.Rows)
+}
+
+func TestCSV_SniffsDelimiterAndHeader(t *testing.T) {
+	db, _ := newCSVDatabase(t, map[string]string{
+		"scores.csv": "1;alice;9.5\n2;bob;\n3;carol;7\n",
+	})
+	ctx := context.Background()
+
+	columns, err := db.GetColumns(ctx, drivers.TableName{Name: "scores"})
+	require.NoError(t, err)
+	require.Len(t, columns, 3)
+	assert.Equal(t, "column1", columns[0].Name)
+	assert.Equal(t, "REAL", columns[2].Type)
+
+	result := db.ExecuteQuery(ctx, "SELECT column2 FROM scores WHERE column3 IS NULL;")
+	require.Nil(t, result.Error)
+	assert.Equal(t, [][]string{{"bob"}}, result.Rows)
+}
+
+func TestCSV_URLOptions(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pairs.csv"), []byte("a|b\nc|d\n"), 0o644))
+
+	db := &drivers.CSV{}
+	require.NoError(t, db.Connect(context.Background(), "csv://"+dir+"?delimiter=|&header=false"))
+	defer db.CloseConnection()
+
+	result := db.ExecuteQuery(context.Background(), "SELECT column1, column2 FROM pairs;")
+	require.Nil(t, result.Error)
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}}, result.Rows)
+
+	_, _, err := drivers.ParseCSVURL("csv://./exports?delimiter=ab")
+	assert.Error(t, err)
+	_, _, err = drivers.ParseCSVURL("csv://./exports?sheet=1")
+	assert.Error(t, err)
+
+	path, options, err := drivers.ParseCSVURL("tsv://./exports")
+	require.NoError(t, err)
+	assert.Equal(t, "./exports", path)
+	assert.Equal(t, '\t', options.Delimiter)
+
+	// A semicolon is named or escaped, a raw ; isn't valid in a query
+	for _, delimiter := range []string{"semicolon", "%3B"} {
+		_, options, err = drivers.ParseCSVURL("csv://./exports?delimiter=" + delimiter)
+		require.NoError(t, err, delimiter)
+		assert.Equal(t, ';', options.Delimiter, delimiter)
+	}
+}
+
+func TestCSV_ConnectErrors(t *testing.T) {
+	db := &drivers.CSV{}
+
+	err := db.Connect(context.Background(), "csv://"+filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorContains(t, err, "does not exist")
+
+	err = db.Connect(context.Background(), "csv://"+t.TempDir())
+	assert.ErrorContains(t, err, "No .csv or .tsv files")
+}
@@ -0,0 +1,148 @@
+package drivers_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const duckDBFixture = `
+CREATE TABLE authors (id INTEGER PRIMARY KEY, name VARCHAR NOT NULL);
+CREATE TABLE books (
+	id INTEGER,
+	edition INTEGER DEFAULT 1,
+	author_id INTEGER REFERENCES authors(id),
+	title VARCHAR UNIQUE,
+	PRIMARY KEY (id, edition)
+);
+CREATE INDEX idx_books_author ON books (author_id, title);
+CREATE SCHEMA archive;
+CREATE TABLE archive.orders (id INTEGER);
+CREATE VIEW book_titles AS SELECT title FROM books;
+`
+
+func newDuckDBDatabase(t *testing.T) *drivers.DuckDB {
+	t.Helper()
+
+	db := &drivers.DuckDB{}
+	require.NoError(t, db.Connect(context.Background(), "duckdb::memory:"))
+	t.Cleanup(func() { db.CloseConnection() })
+
+	result := drivers.ExecuteScript(context.Background(), db, drivers.SplitStatements(duckDBFixture, drivers.DialectOf(db)), true)
+	for _, r := range result.Results {
+		require.Nil(t, r.Error, r.Query)
+	}
+
+	return db
+}
+
+func TestDuckDB_GetTablesAndColumns(t *testing.T) {
+	db := newDuckDBDatabase(t)
+	ctx := context.Background()
+
+	name, err := db.GetDatabaseName(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "memory", name)
+
+	tables, err := db.GetTables(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []drivers.TableName{{Schema: "archive", Name: "orders"}, {Name: "authors"}, {Name: "books"}}, tables)
+
+	columns, err := db.GetColumns(ctx, drivers.TableName{Name: "books"})
+	require.NoError(t, err)
+	require.Len(t, columns, 4)
+	assert.Equal(t, drivers.Column{Name: "id", Type: "INTEGER", PrimaryKey: 1}, columns[0])
+	assert.Equal(t, drivers.Column{Name: "edition", Type: "INTEGER", Default: "1", HasDefault: true, PrimaryKey: 2}, columns[1])
+	assert.True(t, columns[2].Nullable)
+
+	columns, err = db.GetColumns(ctx, drivers.TableName{Schema: "archive", Name: "orders"})
+	require.NoError(t, err)
+	assert.Len(t, columns, 1)
+}
+
+func TestDuckDB_GetIndexesForeignKeysAndViews(t *testing.T) {
+	db := newDuckDBDatabase(t)
+	ctx := context.Background()
+
+	indexes, err := db.GetIndexes(ctx, drivers.TableName{Name: "books"})
+	require.NoError(t, err)
+
+	byName := map[string]drivers.Index{}
+	for _, index := range indexes {
+		byName[index.Name] = index
+	}
+	assert.Equal(t, drivers.Index{Name: "books_id_edition_pkey", Columns: []string{"id", "edition"}, Unique: true, Primary: true}, byName["books_id_edition_pkey"])
+	assert.Equal(t, drivers.Index{Name: "books_title_key", Columns: []string{"title"}, Unique: true}, byName["books_title_key"])
+	assert.Equal(t, drivers.Index{Name: "idx_books_author", Columns: []string{"author_id", "title"}}, byName["idx_books_author"])
+
+	foreignKeys, err := db.GetForeignKeys(ctx, drivers.TableName{Name: "books"})
+	require.NoError(t, err)
+	require.Len(t, foreignKeys, 1)
+	assert.Equal(t, []string{"author_id"}, foreignKeys[0].Columns)
+	assert.Equal(t, "authors", foreignKeys[0].RefTable)
+	assert.Equal(t, []string{"id"}, foreignKeys[0].RefColumns)
+
+	views, err := db.GetViews(ctx)
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	assert.Equal(t, "book_titles", views[0].Name)
+	assert.Contains(t, views[0].Definition, "SELECT title FROM books")
+}
+
+func TestDuckDB_ExecuteQueryFormatsDuckDBTypes(t *testing.T) {
+	db := newDuckDBDatabase(t)
+
+	result := db.ExecuteQuery(context.Background(), `
+		SELECT 123.45::DECIMAL(10,2), -0.05::DECIMAL(4,2), 170141183460469231731687303715884105727::HUGEINT,
+			INTERVAL '1 year 2 months 3 days 04:05:06.5', '8b1ed7f2-2b1a-4f3c-9d4e-5a6b7c8d9e0f'::UUID, 7::TINYINT;`)
+	require.Nil(t, result.Error)
+	assert.Equal(t, [][]string{{
+		"123.45", "-0.05", "170141183460469231731687303715884105727",
+		"1 year 2 months 3 days 04:05:06.5", "8b1ed7f2-2b1a-4f3c-9d4e-5a6b7c8d9e0f", "7",
+	}}, result.Rows)
+	assert.Equal(t, drivers.NumberCell, result.Cells[0][0].Kind)
+	assert.Equal(t, drivers.NumberCell, result.Cells[0][5].Kind)
+}
+
+func TestDuckDB_QueriesLocalFiles(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "sales.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("region,amount\nnorth,10\nsouth,32\n"), 0o644))
+
+	db := &drivers.DuckDB{}
+	dbPath := filepath.Join(dir, "analytics.duckdb")
+
+	// A missing database file is only created when asked for
+	err := db.Connect(context.Background(), "duckdb://"+dbPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "create=true")
+
+	require.NoError(t, db.Connect(context.Background(), "duckdb://"+dbPath+"?create=true"))
+	defer db.CloseConnection()
+
+	result := db.ExecuteQuery(context.Background(), "SELECT sum(amount) FROM read_csv('"+csvPath+"');")
+	require.Nil(t, result.Error)
+	assert.Equal(t, [][]string{{"42"}}, result.Rows)
+
+	name, err := db.GetDatabaseName(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "analytics", name)
+}
+
+func TestDuckDB_ManualCommitTransaction(t *testing.T) {
+	db := newDuckDBDatabase(t)
+	ctx := context.Background()
+
+	require.NoError(t, db.BeginTransaction(ctx))
+	require.Nil(t, db.ExecuteQuery(ctx, "INSERT INTO authors VALUES (1, 'ursula');").Error)
+	require.NoError(t, db.Rollback())
+
+	result := db.ExecuteQuery(ctx, "SELECT count(*) FROM authors;")
+	require.Nil(t, result.Error)
+	assert.Equal(t, [][]string{{"0"}}, result.Rows)
+}
@@ -0,0 +1,95 @@
+package drivers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/jdkingsbury/americano/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaceholders(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"anonymous", "SELECT * FROM users WHERE id = ? AND name = ?;", []string{"?1", "?2"}},
+		{"numbered question marks", "SELECT ?2, ?, ?1;", []string{"?2", "?3", "?1"}},
+		{"named", "SELECT * FROM t WHERE a = :id OR b = :id AND c = :other_id;", []string{":id", ":other_id"}},
+		{"dollar", "SELECT * FROM t WHERE a = $2 AND b = $1;", []string{"$2", "$1"}},
+		{"ignores strings and comments", "SELECT '?', ':name', \"$1\" -- ?\n/* :x */ FROM t WHERE a = ?;", []string{"?1"}},
+		{"ignores casts and assignments", "SELECT a::int, @x := 1 FROM t;", nil},
+		{"ignores dollar quotes", "SELECT $body$ :x ? $body$, $1;", []string{"$1"}},
+		{"ignores identifiers", "SELECT price$1, a:b FROM t;", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, drivers.Placeholders(tt.query, drivers.Dialect{}))
+		})
+	}
+}
+
+func TestPlaceholders_PostgresLeavesJSONBOperators(t *testing.T) {
+	query := "SELECT * FROM t WHERE doc ? 'a' AND doc ?| array['b'] AND doc ?& array[:key] AND id = $1;"
+	assert.Equal(t, []string{":key", "$1"}, drivers.Placeholders(query, drivers.DialectOf(&drivers.Postgres{})))
+	assert.Equal(t, drivers.QuestionPlaceholders, drivers.DialectOf(tests.NewSQLiteDatabase(t)).Placeholders)
+}
+
+func TestPlaceholders_BackslashEscapes(t *testing.T) {
+	// The string ends at the second quote, the ? after it is a placeholder
+	query := `SELECT 'it\'s ?', ? FROM t WHERE a = E'\' :x' AND b = :y;`
+	assert.Equal(t, []string{"?1", ":y"}, drivers.Placeholders(query, drivers.DialectOf(&drivers.MySQL{})))
+
+	// Without backslash escapes 'C:\' is a whole string
+	assert.Equal(t, []string{"?1"}, drivers.Placeholders(`SELECT 'C:\', ?;`, drivers.Dialect{}))
+}
+
+func TestParseParamValue(t *testing.T) {
+	assert.Nil(t, drivers.ParseParamValue("null"))
+	assert.Equal(t, int64(42), drivers.ParseParamValue("42"))
+	assert.Equal(t, 1.5, drivers.ParseParamValue("1.5"))
+	assert.Equal(t, "42", drivers.ParseParamValue("'42'"))
+	assert.Equal(t, "it's", drivers.ParseParamValue("'it''s'"))
+	assert.Equal(t, "Inf", drivers.ParseParamValue("Inf"))
+	assert.Equal(t, "alice", drivers.ParseParamValue("alice"))
+}
+
+func TestSQLite_ExecuteQueryWithArgs(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+
+	require.Nil(t, db.ExecuteQuery(ctx, "CREATE TABLE users (id INTEGER, name TEXT);").Error)
+
+	result := db.ExecuteQueryWithArgs(ctx, "INSERT INTO users VALUES (?, ?), (:id + 1, :name);", map[string]any{
+		"?1": int64(1), "?2": "alice", ":id": int64(1), ":name": "bob",
+	})
+	require.Nil(t, result.Error)
+	assert.Equal(t, int64(2), result.RowsAffected)
+
+	result = db.ExecuteQueryWithArgs(ctx, "SELECT name FROM users WHERE id = $1 OR id = $1 + 1 ORDER BY id;", map[string]any{"$1": int64(1)})
+	require.Nil(t, result.Error)
+	assert.Equal(t, [][]string{{"alice"}, {"bob"}}, result.Rows)
+
+	result = db.ExecuteQueryWithArgs(ctx, "SELECT * FROM users WHERE id = :id;", map[string]any{})
+	assert.EqualError(t, result.Error, "no value given for placeholder :id")
+}
+
+func TestExecuteScriptWithArgs_NumbersAnonymousPlaceholdersAcrossStatements(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+
+	script := "CREATE TABLE t (a, b); INSERT INTO t VALUES (?, ?); SELECT a + b + ? FROM t;"
+	require.Equal(t, []string{"?1", "?2", "?3"}, drivers.Placeholders(script, drivers.DialectOf(db)))
+
+	result := drivers.ExecuteScriptWithArgs(ctx, db, drivers.SplitStatements(script, drivers.DialectOf(db)), map[string]any{
+		"?1": int64(1), "?2": int64(2), "?3": int64(3),
+	}, true)
+
+	require.Len(t, result.Results, 3)
+	require.Nil(t, result.Results[2].Error)
+	assert.Equal(t, [][]string{{"6"}}, result.Results[2].Rows)
+}
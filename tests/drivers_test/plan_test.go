@@ -0,0 +1,79 @@
+package drivers_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/jdkingsbury/americano/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Finds the first plan step whose detail starts with prefix
+func planStep(t *testing.T, nodes []drivers.PlanNode, prefix string) drivers.PlanNode {
+	t.Helper()
+
+	for _, node := range nodes {
+		if strings.HasPrefix(node.Detail, prefix) {
+			return node
+		}
+	}
+
+	t.Fatalf("no plan step starting with %q in %+v", prefix, nodes)
+	return drivers.PlanNode{}
+}
+
+func TestSQLite_ExplainQuery(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+
+	result := drivers.ExecuteScript(ctx, db, drivers.SplitStatements(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER, total REAL);
+		CREATE INDEX orders_user ON orders (user_id);
+	`, drivers.DialectOf(db)), true)
+	for _, r := range result.Results {
+		require.Nil(t, r.Error, r.Query)
+	}
+
+	plan := db.ExplainQuery(ctx, "SELECT * FROM users ORDER BY name;", nil)
+	require.NoError(t, plan.Error)
+	assert.Equal(t, drivers.PlanFullTableScan, planStep(t, plan.Nodes, "SCAN users").Warning)
+	assert.Equal(t, drivers.PlanTempBTree, planStep(t, plan.Nodes, "USE TEMP B-TREE").Warning)
+	assert.Equal(t, 2, drivers.PlanWarnings(plan.Nodes))
+
+	// Index lookups are fine, placeholders are bound like when running the query
+	plan = db.ExplainQuery(ctx, "SELECT u.name, o.total FROM users u JOIN orders o ON o.user_id = u.id WHERE u.id = :id", map[string]any{":id": 1})
+	require.NoError(t, plan.Error)
+	assert.Equal(t, 0, drivers.PlanWarnings(plan.Nodes), "%+v", plan.Nodes)
+	planStep(t, plan.Nodes, "SEARCH o USING INDEX orders_user")
+
+	// Nested steps point at their parent
+	plan = db.ExplainQuery(ctx, "SELECT * FROM users WHERE id IN (SELECT user_id FROM orders WHERE total > 10)", nil)
+	require.NoError(t, plan.Error)
+	subquery := planStep(t, plan.Nodes, "SCAN orders")
+	assert.NotZero(t, subquery.Parent)
+	assert.Equal(t, drivers.PlanFullTableScan, subquery.Warning)
+
+	plan = db.ExplainQuery(ctx, "SELECT * FROM missing", nil)
+	assert.Error(t, plan.Error)
+}
+
+func TestDuckDB_ExplainQuery(t *testing.T) {
+	db := newDuckDBDatabase(t)
+	ctx := context.Background()
+
+	// Empty tables are optimized away
+	require.Nil(t, db.ExecuteQuery(ctx, "INSERT INTO authors VALUES (1, 'ursula'), (2, 'octavia');").Error)
+
+	plan := db.ExplainQuery(ctx, "SELECT name FROM authors WHERE name LIKE 'u%' ORDER BY name;", nil)
+	require.NoError(t, plan.Error)
+
+	scan := planStep(t, plan.Nodes, "SEQ_SCAN authors")
+	assert.Equal(t, drivers.PlanFullTableScan, scan.Warning)
+	assert.NotZero(t, scan.Parent)
+	assert.Equal(t, 0, plan.Nodes[0].Parent)
+	assert.Equal(t, 1, drivers.PlanWarnings(plan.Nodes))
+}
@@ -0,0 +1,94 @@
+package drivers_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/jdkingsbury/americano/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnly_RejectsWriteStatements(t *testing.T) {
+	path := tests.NewSQLiteFile(t, "snapshot.db",
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);",
+		"INSERT INTO users (name) VALUES ('ada');",
+	)
+
+	db, msg := drivers.ConnectToDatabase(context.Background(), "sqlite://"+path, drivers.Limits{ReadOnly: true})
+	require.NotNil(t, db, "%v", msg)
+	defer db.CloseConnection()
+
+	ctx := context.Background()
+	for _, query := range []string{
+		"SELECT name FROM users;",
+		"WITH named AS (SELECT name FROM users) SELECT * FROM named",
+		"EXPLAIN QUERY PLAN SELECT * FROM users",
+		"PRAGMA table_info(users)",
+		"PRAGMA main.index_list('users')",
+		"SELECT 'insert into users' AS words",
+	} {
+		assert.Nil(t, db.ExecuteQuery(ctx, query).Error, query)
+	}
+
+	for _, query := range []string{
+		"INSERT INTO users (name) VALUES ('bob');",
+		"update users set name = 'bob'",
+		"DROP TABLE users",
+		"WITH gone AS (SELECT id FROM users) DELETE FROM users WHERE id IN gone",
+		"PRAGMA user_version = 5",
+		"PRAGMA user_version(5)",
+		"PRAGMA main.journal_mode(DELETE)",
+		"ATTACH DATABASE 'other.db' AS other",
+	} {
+		result := db.ExecuteQuery(ctx, query)
+		assert.ErrorIs(t, result.Error, drivers.ErrReadOnly, query)
+	}
+
+	// Scripts stop at the first write
+	script := drivers.ExecuteScript(ctx, db, drivers.SplitStatements("SELECT 1; DELETE FROM users; SELECT 2;", drivers.DialectOf(db)), true)
+	require.Len(t, script.Results, 2)
+	assert.ErrorIs(t, script.Results[1].Error, drivers.ErrReadOnly)
+}
+
+func TestReadOnly_OpensSQLiteReadOnly(t *testing.T) {
+	path := tests.NewSQLiteFile(t, "snapshot.db", "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);")
+
+	db, msg := drivers.ConnectToDatabase(context.Background(), "sqlite://"+path, drivers.Limits{ReadOnly: true})
+	require.NotNil(t, db, "%v", msg)
+	defer db.CloseConnection()
+
+	// Writes that get past the statement check are refused by sqlite itself
+	_, err := db.(*drivers.SQLite).Connection.Exec("INSERT INTO users (name) VALUES ('bob')")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "readonly")
+}
+
+func TestReadOnly_OpensDuckDBReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.duckdb")
+
+	db, msg := drivers.ConnectToDatabase(context.Background(), "duckdb://"+path+"?create=true", drivers.Limits{})
+	require.NotNil(t, db, "%v", msg)
+	require.Nil(t, db.ExecuteQuery(context.Background(), "CREATE TABLE users (id INTEGER);").Error)
+	require.NoError(t, db.CloseConnection())
+
+	db, msg = drivers.ConnectToDatabase(context.Background(), "duckdb://"+path, drivers.Limits{ReadOnly: true})
+	require.NotNil(t, db, "%v", msg)
+	defer db.CloseConnection()
+
+	_, err := db.(*drivers.DuckDB).Connection.Exec("INSERT INTO users VALUES (1)")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read-only")
+}
+
+func TestReadOnly_OpensInMemoryDuckDB(t *testing.T) {
+	db, msg := drivers.ConnectToDatabase(context.Background(), "duckdb://:memory:", drivers.Limits{ReadOnly: true})
+	require.NotNil(t, db, "%v", msg)
+	defer db.CloseConnection()
+
+	ctx := context.Background()
+	assert.Nil(t, db.ExecuteQuery(ctx, "SELECT 1").Error)
+	assert.ErrorIs(t, db.ExecuteQuery(ctx, "CREATE TABLE users (id INTEGER)").Error, drivers.ErrReadOnly)
+}
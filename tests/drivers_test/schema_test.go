@@ -0,0 +1,120 @@
+package drivers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/jdkingsbury/americano/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const schemaFixture = `
+CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+CREATE TABLE books (
+	id INTEGER,
+	edition INTEGER DEFAULT 1,
+	author_id INTEGER REFERENCES authors(id) ON DELETE CASCADE,
+	title TEXT,
+	PRIMARY KEY (id, edition)
+);
+CREATE UNIQUE INDEX idx_books_title ON books (title, author_id);
+CREATE VIEW book_titles AS SELECT title FROM books;
+CREATE TRIGGER books_touch AFTER INSERT ON books BEGIN SELECT 1; END;
+`
+
+func newSchemaDatabase(t *testing.T) *drivers.SQLite {
+	db := tests.NewSQLiteDatabase(t)
+	result := drivers.ExecuteScript(context.Background(), db, drivers.SplitStatements(schemaFixture, drivers.DialectOf(db)), true)
+	for _, r := range result.Results {
+		require.Nil(t, r.Error, r.Query)
+	}
+	return db
+}
+
+func TestSQLite_GetColumns(t *testing.T) {
+	db := newSchemaDatabase(t)
+
+	columns, err := db.GetColumns(context.Background(), drivers.TableName{Name: "books"})
+	require.NoError(t, err)
+	require.Len(t, columns, 4)
+
+	assert.Equal(t, drivers.Column{Name: "id", Type: "INTEGER", Nullable: true, PrimaryKey: 1}, columns[0])
+	assert.Equal(t, drivers.Column{Name: "edition", Type: "INTEGER", Nullable: true, Default: "1", HasDefault: true, PrimaryKey: 2}, columns[1])
+	assert.Equal(t, []string{"id", "edition"}, drivers.PrimaryKey(columns))
+
+	columns, err = db.GetColumns(context.Background(), drivers.TableName{Name: "authors"})
+	require.NoError(t, err)
+	assert.False(t, columns[1].Nullable)
+}
+
+func TestSQLite_TableNamesWithDots(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+	require.Nil(t, db.ExecuteQuery(ctx, `CREATE TABLE "v1.orders" (id INTEGER PRIMARY KEY);`).Error)
+
+	// The dot is part of the name, the table is in main
+	tables, err := db.GetTables(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []drivers.TableName{{Name: "v1.orders"}}, tables)
+
+	columns, err := db.GetColumns(ctx, tables[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id"}, drivers.PrimaryKey(columns))
+}
+
+func TestSQLite_GetIndexesAndForeignKeys(t *testing.T) {
+	db := newSchemaDatabase(t)
+	ctx := context.Background()
+
+	indexes, err := db.GetIndexes(ctx, drivers.TableName{Name: "books"})
+	require.NoError(t, err)
+
+	var unique drivers.Index
+	var primary bool
+	for _, index := range indexes {
+		if index.Name == "idx_books_title" {
+			unique = index
+		}
+		primary = primary || index.Primary
+	}
+	assert.True(t, unique.Unique)
+	assert.Equal(t, []string{"title", "author_id"}, unique.Columns)
+	assert.True(t, primary, "composite primary key should be reported as an index")
+
+	foreignKeys, err := db.GetForeignKeys(ctx, drivers.TableName{Name: "books"})
+	require.NoError(t, err)
+	require.Len(t, foreignKeys, 1)
+	assert.Equal(t, []string{"author_id"}, foreignKeys[0].Columns)
+	assert.Equal(t, "authors", foreignKeys[0].RefTable)
+	assert.Equal(t, []string{"id"}, foreignKeys[0].RefColumns)
+	assert.Equal(t, "CASCADE", foreignKeys[0].OnDelete)
+}
+
+func TestSQLite_GetViewsAndTriggers(t *testing.T) {
+	db := newSchemaDatabase(t)
+	ctx := context.Background()
+
+	views, err := db.GetViews(ctx)
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	assert.Equal(t, "book_titles", views[0].Name)
+	assert.Contains(t, views[0].Definition, "SELECT title FROM books")
+
+	triggers, err := db.GetTriggers(ctx)
+	require.NoError(t, err)
+	require.Len(t, triggers, 1)
+	assert.Equal(t, drivers.Trigger{
+		Name:       "books_touch",
+		Table:      "books",
+		Definition: "CREATE TRIGGER books_touch AFTER INSERT ON books BEGIN SELECT 1; END",
+	}, triggers[0])
+}
+
+func TestDialect_QuoteTable(t *testing.T) {
+	table := drivers.TableName{Schema: "archive", Name: `my "odd" table`}
+
+	assert.Equal(t, `"archive"."my ""odd"" table"`, drivers.Dialect{}.QuoteTable(table))
+	assert.Equal(t, "`orders`", drivers.Dialect{BacktickIdentifiers: true}.QuoteTable(drivers.TableName{Name: "orders"}))
+}
@@ -0,0 +1,117 @@
+package drivers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/jdkingsbury/americano/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected []string
+	}{
+		{
+			name:     "single statement",
+			script:   "SELECT * FROM users;",
+			expected: []string{"SELECT * FROM users;"},
+		},
+		{
+			name:     "missing final semicolon",
+			script:   "SELECT 1;\nSELECT 2",
+			expected: []string{"SELECT 1;", "SELECT 2"},
+		},
+		{
+			name:     "semicolons in strings and identifiers",
+			script:   `INSERT INTO notes VALUES ('a;b', 'it''s; fine'); SELECT "odd;name" FROM notes;`,
+			expected: []string{`INSERT INTO notes VALUES ('a;b', 'it''s; fine');`, `SELECT "odd;name" FROM notes;`},
+		},
+		{
+			name:     "semicolons in comments",
+			script:   "-- first; statement\nSELECT 1; /* second; statement */ SELECT 2;",
+			expected: []string{"-- first; statement\nSELECT 1;", "/* second; statement */ SELECT 2;"},
+		},
+		{
+			name:     "comment only statements are dropped",
+			script:   "SELECT 1;\n-- trailing comment\n",
+			expected: []string{"SELECT 1;"},
+		},
+		{
+			name: "trigger body",
+			script: `CREATE TRIGGER log_update AFTER UPDATE ON users
+BEGIN
+	INSERT INTO audit VALUES (CASE WHEN new.name IS NULL THEN 'none' ELSE new.name END);
+	UPDATE stats SET updates = updates + 1;
+END;
+SELECT 1;`,
+			expected: []string{`CREATE TRIGGER log_update AFTER UPDATE ON users
+BEGIN
+	INSERT INTO audit VALUES (CASE WHEN new.name IS NULL THEN 'none' ELSE new.name END);
+	UPDATE stats SET updates = updates + 1;
+END;`, "SELECT 1;"},
+		},
+		{
+			name:     "transaction begin is its own statement",
+			script:   "BEGIN; UPDATE users SET name = 'a'; COMMIT;",
+			expected: []string{"BEGIN;", "UPDATE users SET name = 'a';", "COMMIT;"},
+		},
+		{
+			name:     "dollar quoted function body",
+			script:   "CREATE FUNCTION one() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql; SELECT $1;",
+			expected: []string{"CREATE FUNCTION one() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql;", "SELECT $1;"},
+		},
+		{
+			name:     "procedure with end if",
+			script:   "CREATE PROCEDURE p() BEGIN IF 1 THEN SELECT 1; END IF; END; CALL p();",
+			expected: []string{"CREATE PROCEDURE p() BEGIN IF 1 THEN SELECT 1; END IF; END;", "CALL p();"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, drivers.SplitStatements(tt.script, drivers.Dialect{}))
+		})
+	}
+}
+
+func TestSplitStatements_BackslashEscapes(t *testing.T) {
+	mysql := drivers.DialectOf(&drivers.MySQL{})
+	assert.Equal(t, []string{`SELECT 'it\'s; fine';`, "SELECT 2;"}, drivers.SplitStatements(`SELECT 'it\'s; fine'; SELECT 2;`, mysql))
+	assert.Equal(t, []string{`SELECT "a\"; b";`, "SELECT 2;"}, drivers.SplitStatements(`SELECT "a\"; b"; SELECT 2;`, mysql))
+
+	// Postgres only reads backslash escapes in E'...' strings
+	postgres := drivers.DialectOf(&drivers.Postgres{})
+	assert.Equal(t, []string{`SELECT E'it\'s; fine';`, "SELECT 2;"}, drivers.SplitStatements(`SELECT E'it\'s; fine'; SELECT 2;`, postgres))
+	assert.Equal(t, []string{`SELECT 'C:\';`, "SELECT 2;"}, drivers.SplitStatements(`SELECT 'C:\'; SELECT 2;`, postgres))
+	assert.Equal(t, []string{`SELECT name'x\';`, "SELECT 2;"}, drivers.SplitStatements(`SELECT name'x\'; SELECT 2;`, postgres))
+}
+
+func TestExecuteScript_StopOnError(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	statements := drivers.SplitStatements("CREATE TABLE users (id INTEGER); INSERT INTO missing VALUES (1); INSERT INTO users VALUES (1);", drivers.DialectOf(db))
+
+	script := drivers.ExecuteScript(context.Background(), db, statements, true)
+
+	require.Len(t, script.Results, 2)
+	assert.Equal(t, 3, script.Total)
+	assert.Nil(t, script.Results[0].Error)
+	assert.Error(t, script.Results[1].Error)
+	assert.Equal(t, "INSERT INTO missing VALUES (1);", script.Results[1].Query)
+}
+
+func TestExecuteScript_ContinueOnError(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	statements := drivers.SplitStatements("CREATE TABLE users (id INTEGER); INSERT INTO missing VALUES (1); INSERT INTO users VALUES (1);", drivers.DialectOf(db))
+
+	script := drivers.ExecuteScript(context.Background(), db, statements, false)
+
+	require.Len(t, script.Results, 3)
+	assert.Error(t, script.Results[1].Error)
+	assert.Nil(t, script.Results[2].Error)
+	assert.Equal(t, int64(1), script.Results[2].RowsAffected)
+}
@@ -0,0 +1,66 @@
+package drivers_test
+
+import (
+	"testing"
+
+	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/jdkingsbury/americano/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	drivers.Register("sessiontest", drivers.Driver{
+		Name:       "Session Test",
+		ExampleURL: "sessiontest://db",
+		New:        func() drivers.Database { return &tests.MockDatabase{} },
+	})
+}
+
+func TestSessionManager_SwitchClosesPreviousConnection(t *testing.T) {
+	session := drivers.NewSessionManager()
+
+	id := session.Open("first")
+	assert.Equal(t, drivers.Connecting, session.State())
+
+	first, _, current := session.Connect(id, "sessiontest://first", drivers.Limits{})
+	require.True(t, current)
+	assert.Equal(t, drivers.Connected, session.State())
+	assert.Same(t, first, session.Database())
+
+	id = session.Open("second")
+	assert.True(t, first.(*tests.MockDatabase).Closed)
+	assert.Nil(t, session.Database())
+
+	second, _, current := session.Connect(id, "sessiontest://second", drivers.Limits{})
+	require.True(t, current)
+	assert.Equal(t, "second", session.Name())
+
+	require.NoError(t, session.Close())
+	assert.True(t, second.(*tests.MockDatabase).Closed)
+	assert.Equal(t, drivers.Disconnected, session.State())
+}
+
+func TestSessionManager_StaleConnectIsDiscarded(t *testing.T) {
+	session := drivers.NewSessionManager()
+
+	stale := session.Open("first")
+	session.Open("second")
+
+	db, _, current := session.Connect(stale, "sessiontest://first", drivers.Limits{})
+	assert.False(t, current)
+	assert.Nil(t, db)
+	assert.Nil(t, session.Database())
+}
+
+func TestSessionManager_FailedConnect(t *testing.T) {
+	session := drivers.NewSessionManager()
+
+	id := session.Open("broken")
+	db, msg, current := session.Connect(id, "nosuchdriver://db", drivers.Limits{})
+
+	assert.True(t, current)
+	assert.Nil(t, db)
+	assert.NotNil(t, msg)
+	assert.Equal(t, drivers.ConnectionFailed, session.State())
+}
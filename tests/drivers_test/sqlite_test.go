@@ -0,0 +1,339 @@
+package drivers_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/jdkingsbury/americano/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_ExecuteQueryCancelled(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel a runaway query shortly after it starts
+	time.AfterFunc(50*time.Millisecond, cancel)
+	result := db.ExecuteQuery(ctx, "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c;")
+
+	assert.ErrorIs(t, result.Error, drivers.ErrQueryCancelled)
+}
+
+func TestSQLite_ExecuteQueryReportsRowsAffected(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+
+	result := db.ExecuteQuery(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);")
+	require.Nil(t, result.Error)
+	assert.True(t, result.IsExec)
+
+	result = db.ExecuteQuery(ctx, "INSERT INTO users (name) VALUES ('alice'), ('bob');")
+	require.Nil(t, result.Error)
+	assert.True(t, result.IsExec)
+	assert.Equal(t, int64(2), result.RowsAffected)
+	assert.Equal(t, int64(2), result.LastInsertId)
+
+	result = db.ExecuteQuery(ctx, "-- rename everyone\nUPDATE users SET name = 'select';")
+	require.Nil(t, result.Error)
+	assert.True(t, result.IsExec)
+	assert.Equal(t, int64(2), result.RowsAffected)
+
+	result = db.ExecuteQuery(ctx, "SELECT name FROM users WHERE id = 1;")
+	require.Nil(t, result.Error)
+	assert.False(t, result.IsExec)
+	assert.Equal(t, [][]string{{"select"}}, result.Rows)
+
+	result = db.ExecuteQuery(ctx, "DELETE FROM users WHERE id = 2 RETURNING name;")
+	require.Nil(t, result.Error)
+	assert.False(t, result.IsExec)
+	assert.Equal(t, []string{"name"}, result.Columns)
+}
+
+func TestSQLite_ExecuteQueryTypedCells(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+
+	require.Nil(t, db.ExecuteQuery(ctx, "CREATE TABLE files (id INTEGER NOT NULL, name TEXT, data BLOB, price REAL, created DATETIME);").Error)
+	require.Nil(t, db.ExecuteQuery(ctx, "INSERT INTO files VALUES (1, 'NULL', x'00ff', 1.5, '2024-05-01 13:45:00'), (2, NULL, NULL, NULL, NULL);").Error)
+
+	result := db.ExecuteQuery(ctx, "SELECT id, name, data, price, created FROM files ORDER BY id;")
+
+	require.Nil(t, result.Error)
+	require.Len(t, result.ColumnInfo, 5)
+	assert.Equal(t, "INTEGER", result.ColumnInfo[0].DatabaseType)
+	assert.Equal(t, "BLOB", result.ColumnInfo[2].DatabaseType)
+
+	require.Len(t, result.Cells, 2)
+	assert.Equal(t, drivers.NumberCell, result.Cells[0][0].Kind)
+	assert.Equal(t, drivers.TextCell, result.Cells[0][1].Kind)
+	assert.Equal(t, drivers.BytesCell, result.Cells[0][2].Kind)
+	assert.Equal(t, drivers.NumberCell, result.Cells[0][3].Kind)
+	assert.Equal(t, drivers.TimeCell, result.Cells[0][4].Kind)
+	assert.Equal(t, []string{"1", "NULL", "0x00ff", "1.5", "2024-05-01 13:45:00"}, result.Rows[0])
+
+	// A real NULL is distinguishable from the string "NULL"
+	assert.Equal(t, drivers.NullCell, result.Cells[1][1].Kind)
+}
+
+func TestSQLite_ExecuteQueryFormatsDatesByColumnType(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+
+	require.Nil(t, db.ExecuteQuery(ctx, "CREATE TABLE events (day DATE, at DATETIME);").Error)
+	require.Nil(t, db.ExecuteQuery(ctx, "INSERT INTO events VALUES ('2024-05-01', '2024-05-01 00:00:00');").Error)
+
+	result := db.ExecuteQuery(ctx, "SELECT day, at FROM events;")
+
+	require.Nil(t, result.Error)
+	assert.Equal(t, drivers.TimeCell, result.Cells[0][0].Kind)
+	assert.Equal(t, []string{"2024-05-01", "2024-05-01 00:00:00"}, result.Rows[0])
+}
+
+func TestSQLite_ExecuteQueryPagesLargeResults(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+
+	require.Nil(t, db.ExecuteQuery(ctx, "CREATE TABLE numbers (n INTEGER);").Error)
+	require.Nil(t, db.ExecuteQuery(ctx, "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 450) INSERT INTO numbers SELECT x FROM c;").Error)
+
+	result := db.ExecuteQuery(ctx, "SELECT n FROM numbers ORDER BY n;")
+
+	require.Nil(t, result.Error)
+	assert.Len(t, result.Rows, drivers.FirstPageSize)
+	assert.True(t, result.HasMore)
+	require.NotNil(t, result.Cursor)
+
+	rows, cells, hasMore, err := result.Cursor.Fetch(200)
+	require.NoError(t, err)
+	assert.Len(t, rows, 200)
+	assert.Len(t, cells, 200)
+	assert.True(t, hasMore)
+
+	rows, _, hasMore, err = result.Cursor.Fetch(200)
+	require.NoError(t, err)
+	assert.Len(t, rows, 50)
+	assert.Equal(t, []string{"450"}, rows[49])
+	assert.False(t, hasMore)
+}
+
+func TestSQLite_ExecuteQueryClosesPreviousCursor(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+
+	result := db.ExecuteQuery(ctx, "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000) SELECT x FROM c;")
+	require.NotNil(t, result.Cursor)
+
+	// The next statement is not blocked by the open reader
+	require.Nil(t, db.ExecuteQuery(ctx, "CREATE TABLE users (id INTEGER);").Error)
+
+	rows, _, hasMore, err := result.Cursor.Fetch(10)
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+	assert.False(t, hasMore)
+}
+
+// Queries run in tea.Cmd goroutines while commit, rollback and closing the connection
+// run on the update goroutine. Run with -race to catch unguarded cursor access.
+func TestSQLite_CursorSharedAcrossGoroutines(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db.ExecuteQuery(ctx, "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000) SELECT x FROM c;")
+		}()
+	}
+	for range 4 {
+		db.Rollback()
+	}
+	wg.Wait()
+
+	assert.NoError(t, db.CloseConnection())
+}
+
+func TestSQLite_ManualCommitTransaction(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+
+	require.Nil(t, db.ExecuteQuery(ctx, "CREATE TABLE items (id INTEGER PRIMARY KEY);").Error)
+	count := func() string {
+		return db.ExecuteQuery(ctx, "SELECT count(*) FROM items;").Rows[0][0]
+	}
+
+	require.NoError(t, db.BeginTransaction(ctx))
+	assert.True(t, db.InTransaction())
+
+	// Statements share the pinned connection, so a temp table stays visible
+	require.Nil(t, db.ExecuteQuery(ctx, "CREATE TEMP TABLE scratch (id INTEGER);").Error)
+	require.Nil(t, db.ExecuteQuery(ctx, "INSERT INTO items SELECT 1 FROM scratch UNION SELECT 2;").Error)
+	assert.Equal(t, "1", count())
+
+	require.NoError(t, db.Rollback())
+	assert.False(t, db.InTransaction())
+	assert.Equal(t, "0", count())
+
+	require.NoError(t, db.BeginTransaction(ctx))
+	require.Nil(t, db.ExecuteQuery(ctx, "INSERT INTO items VALUES (1);").Error)
+	require.NoError(t, db.Commit())
+	assert.Equal(t, "1", count())
+
+	assert.ErrorIs(t, db.Commit(), drivers.ErrNoTransaction)
+}
+
+func TestSQLite_TransactionOutlivesCancelledStatement(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, db.BeginTransaction(ctx))
+	cancel()
+
+	result := db.ExecuteQuery(context.Background(), "CREATE TABLE items (id INTEGER);")
+	require.Nil(t, result.Error)
+	assert.NoError(t, db.Commit())
+}
+
+func TestSQLite_RowLimitTruncatesResult(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	ctx := context.Background()
+	query := "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 300) SELECT x FROM c;"
+
+	db.SetLimits(drivers.Limits{MaxRows: 5})
+	result := db.ExecuteQuery(ctx, query)
+	require.Nil(t, result.Error)
+	assert.Len(t, result.Rows, 5)
+	assert.False(t, result.HasMore)
+	assert.True(t, result.Truncated)
+
+	// The limit also applies to pages fetched later
+	db.SetLimits(drivers.Limits{MaxRows: 250})
+	result = db.ExecuteQuery(ctx, query)
+	require.NotNil(t, result.Cursor)
+	assert.False(t, result.Truncated)
+
+	rows, _, hasMore, err := result.Cursor.Fetch(200)
+	require.NoError(t, err)
+	assert.Len(t, rows, 50)
+	assert.False(t, hasMore)
+	assert.True(t, result.Cursor.Truncated())
+
+	// A result that fits the limit exactly is not truncated
+	db.SetLimits(drivers.Limits{MaxRows: 300})
+	result = db.ExecuteQuery(ctx, "SELECT x FROM (SELECT 1 AS x UNION ALL SELECT 2);")
+	assert.False(t, result.Truncated)
+}
+
+func TestSQLite_StatementTimeout(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	db.SetLimits(drivers.Limits{StatementTimeout: 50 * time.Millisecond})
+
+	result := db.ExecuteQuery(context.Background(), "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c;")
+
+	assert.ErrorIs(t, result.Error, drivers.ErrStatementTimeout)
+}
+
+func TestParseSQLiteURL(t *testing.T) {
+	config, err := drivers.ParseSQLiteURL("sqlite::memory:")
+	require.NoError(t, err)
+	assert.True(t, config.Memory)
+
+	config, err = drivers.ParseSQLiteURL("sqlite:///var/data/app.db")
+	require.NoError(t, err)
+	assert.Equal(t, "/var/data/app.db", config.Path)
+
+	config, err = drivers.ParseSQLiteURL("sqlite://./dev.db?mode=ro&_foreign_keys=on&create=true")
+	require.NoError(t, err)
+	assert.Equal(t, "./dev.db", config.Path)
+	assert.True(t, config.Create)
+	assert.Equal(t, "ro", config.Params.Get("mode"))
+	assert.Equal(t, "on", config.Params.Get("_foreign_keys"))
+	assert.False(t, config.Params.Has("create"), "create is americano's own option, not go-sqlite3's")
+
+	_, err = drivers.ParseSQLiteURL("sqlite://")
+	assert.Error(t, err)
+	_, err = drivers.ParseSQLiteURL("postgres://localhost/db")
+	assert.Error(t, err)
+	_, err = drivers.ParseSQLiteURL("sqlite://dev.db?create=maybe")
+	assert.Error(t, err)
+}
+
+func TestSQLite_ConnectMemory(t *testing.T) {
+	db := &drivers.SQLite{}
+	require.NoError(t, db.Connect(context.Background(), "sqlite::memory:"))
+	defer db.CloseConnection()
+	ctx := context.Background()
+
+	require.Nil(t, db.ExecuteQuery(ctx, "CREATE TABLE notes (body TEXT);").Error)
+
+	// Tables created through one pooled connection are visible to the others
+	tables, err := db.GetTables(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []drivers.TableName{{Name: "notes"}}, tables)
+
+	// Every connection gets its own in-memory database
+	other := &drivers.SQLite{}
+	require.NoError(t, other.Connect(ctx, "sqlite::memory:"))
+	defer other.CloseConnection()
+	tables, err = other.GetTables(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tables)
+}
+
+func TestSQLite_ConnectRelativePathAndCreate(t *testing.T) {
+	chdir(t, t.TempDir())
+	ctx := context.Background()
+
+	db := &drivers.SQLite{}
+	err := db.Connect(ctx, "sqlite://./dev.db")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "create=true")
+
+	require.NoError(t, db.Connect(ctx, "sqlite://./dev.db?create=true"))
+	defer db.CloseConnection()
+	require.Nil(t, db.ExecuteQuery(ctx, "CREATE TABLE notes (body TEXT);").Error)
+	assert.FileExists(t, "dev.db")
+
+	name, err := db.GetDatabaseName(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "dev.db", name)
+}
+
+func TestSQLite_ConnectPassesOptionsThrough(t *testing.T) {
+	chdir(t, t.TempDir())
+	ctx := context.Background()
+
+	db := &drivers.SQLite{}
+	require.NoError(t, db.Connect(ctx, "sqlite://app.db?create=true&_foreign_keys=on"))
+	require.Nil(t, db.ExecuteQuery(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY);").Error)
+	require.Nil(t, db.ExecuteQuery(ctx, "CREATE TABLE posts (user_id INTEGER REFERENCES users (id));").Error)
+
+	result := db.ExecuteQuery(ctx, "INSERT INTO posts (user_id) VALUES (42);")
+	assert.ErrorContains(t, result.Error, "FOREIGN KEY")
+	db.CloseConnection()
+
+	readOnly := &drivers.SQLite{}
+	require.NoError(t, readOnly.Connect(ctx, "sqlite://app.db?mode=ro"))
+	defer readOnly.CloseConnection()
+
+	result = readOnly.ExecuteQuery(ctx, "INSERT INTO users (id) VALUES (1);")
+	assert.ErrorContains(t, result.Error, "readonly")
+}
+
+// Runs the rest of the test from dir, for relative sqlite paths
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+}
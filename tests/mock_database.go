@@ -1,31 +1,117 @@
 package tests
 
 import (
+	"context"
+
 	"github.com/jdkingsbury/americano/internal/drivers"
 )
 
 type MockDatabase struct {
 	ExecutedQuery string
+	ExecutedArgs  map[string]any
 	QueryResult   drivers.QueryResultMsg
+	// Block makes ExecuteQuery wait until its context is cancelled
+	Block bool
+	// Tables listed by GetTables, mock_table when empty
+	Tables []drivers.TableName
+	Closed bool
+	TxOpen bool
+	// Set by Commit and Rollback
+	Committed  bool
+	RolledBack bool
+	Limits     drivers.Limits
+	// Returned by ExplainQuery
+	QueryPlan      drivers.QueryPlanMsg
+	ExplainedQuery string
 }
 
-func (m *MockDatabase) Connect(url string) error {
+func (m *MockDatabase) Connect(ctx context.Context, url string) error {
 	return nil
 }
 
 func (m *MockDatabase) CloseConnection() error {
+	m.Closed = true
 	return nil
 }
 
-func (m *MockDatabase) ExecuteQuery(query string) drivers.QueryResultMsg {
+func (m *MockDatabase) ExecuteQuery(ctx context.Context, query string) drivers.QueryResultMsg {
 	m.ExecutedQuery = query
+	if m.Block {
+		<-ctx.Done()
+		return drivers.QueryResultMsg{Error: drivers.ErrQueryCancelled}
+	}
 	return m.QueryResult
 }
 
-func (m *MockDatabase) GetDatabaseName() (string, error) {
+func (m *MockDatabase) ExecuteQueryWithArgs(ctx context.Context, query string, args map[string]any) drivers.QueryResultMsg {
+	m.ExecutedArgs = args
+	return m.ExecuteQuery(ctx, query)
+}
+
+func (m *MockDatabase) ExplainQuery(ctx context.Context, query string, args map[string]any) drivers.QueryPlanMsg {
+	m.ExplainedQuery = query
+	m.ExecutedArgs = args
+	return m.QueryPlan
+}
+
+func (m *MockDatabase) SetLimits(limits drivers.Limits) {
+	m.Limits = limits
+}
+
+func (m *MockDatabase) GetDatabaseName(ctx context.Context) (string, error) {
 	return "mock_db", nil
 }
 
-func (m *MockDatabase) GetTables() ([]string, error) {
-	return []string{"mock_table"}, nil
+func (m *MockDatabase) GetTables(ctx context.Context) ([]drivers.TableName, error) {
+	if len(m.Tables) > 0 {
+		return m.Tables, nil
+	}
+	return []drivers.TableName{{Name: "mock_table"}}, nil
+}
+
+func (m *MockDatabase) GetColumns(ctx context.Context, table drivers.TableName) ([]drivers.Column, error) {
+	return []drivers.Column{{Name: "id", Type: "INTEGER", PrimaryKey: 1}}, nil
+}
+
+func (m *MockDatabase) GetIndexes(ctx context.Context, table drivers.TableName) ([]drivers.Index, error) {
+	return nil, nil
+}
+
+func (m *MockDatabase) GetForeignKeys(ctx context.Context, table drivers.TableName) ([]drivers.ForeignKey, error) {
+	return nil, nil
+}
+
+func (m *MockDatabase) GetViews(ctx context.Context) ([]drivers.View, error) {
+	return nil, nil
+}
+
+func (m *MockDatabase) GetTriggers(ctx context.Context) ([]drivers.Trigger, error) {
+	return nil, nil
+}
+
+func (m *MockDatabase) BeginTransaction(ctx context.Context) error {
+	m.TxOpen = true
+	return nil
+}
+
+func (m *MockDatabase) Commit() error {
+	if !m.TxOpen {
+		return drivers.ErrNoTransaction
+	}
+	m.TxOpen = false
+	m.Committed = true
+	return nil
+}
+
+func (m *MockDatabase) Rollback() error {
+	if !m.TxOpen {
+		return drivers.ErrNoTransaction
+	}
+	m.TxOpen = false
+	m.RolledBack = true
+	return nil
+}
+
+func (m *MockDatabase) InTransaction() bool {
+	return m.TxOpen
 }
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// A stand-in for a mysql server that accepts any login and answers pings and SET
+// statements. SET fails with error 1193 for variables it doesn't know, like a real
+// server does, so tests can check what a connection sends.
+type FakeMySQLServer struct {
+	Addr string
+
+	variables map[string]bool
+	mu        sync.Mutex
+	sets      []string
+}
+
+// Starts a fake server that knows the given session variables
+func NewFakeMySQLServer(t *testing.T, variables ...string) *FakeMySQLServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &FakeMySQLServer{Addr: listener.Addr().String(), variables: map[string]bool{}}
+	for _, variable := range variables {
+		server.variables[variable] = true
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+// SET statements received so far
+func (s *FakeMySQLServer) Sets() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.sets...)
+}
+
+func (s *FakeMySQLServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	// Protocol 10 handshake offering mysql_native_password
+	capabilities := uint32(0x1 | 0x200 | 0x2000 | 0x8000 | 0x80000)
+	handshake := []byte{10}
+	handshake = append(handshake, "8.0.36-fake\x00"...)
+	handshake = binary.LittleEndian.AppendUint32(handshake, 1)
+	handshake = append(handshake, "abcdefgh\x00"...)
+	handshake = binary.LittleEndian.AppendUint16(handshake, uint16(capabilities))
+	handshake = append(handshake, 0xff, 0x02, 0x00)
+	handshake = binary.LittleEndian.AppendUint16(handshake, uint16(capabilities>>16))
+	handshake = append(handshake, 21)
+	handshake = append(handshake, make([]byte, 10)...)
+	handshake = append(handshake, "ijklmnopqrst\x00"...)
+	handshake = append(handshake, "mysql_native_password\x00"...)
+	if writePacket(conn, 0, handshake) != nil {
+		return
+	}
+
+	// Any login is accepted
+	if _, _, err := readPacket(r); err != nil {
+		return
+	}
+	if writePacket(conn, 2, okPacket()) != nil {
+		return
+	}
+
+	for {
+		seq, packet, err := readPacket(r)
+		if err != nil || len(packet) == 0 {
+			return
+		}
+
+		var reply []byte
+		switch packet[0] {
+		case 0x01: // COM_QUIT
+			return
+		case 0x03: // COM_QUERY
+			reply = s.query(string(packet[1:]))
+		default:
+			reply = okPacket()
+		}
+
+		if writePacket(conn, seq+1, reply) != nil {
+			return
+		}
+	}
+}
+
+func (s *FakeMySQLServer) query(query string) []byte {
+	set, ok := strings.CutPrefix(query, "SET ")
+	if !ok {
+		return errPacket(1064, "the fake server only runs SET statements")
+	}
+
+	s.mu.Lock()
+	s.sets = append(s.sets, query)
+	s.mu.Unlock()
+
+	for _, assignment := range strings.Split(set, ",") {
+		name, _, _ := strings.Cut(assignment, "=")
+		name = strings.TrimSpace(name)
+		if !s.variables[name] {
+			return errPacket(1193, fmt.Sprintf("Unknown system variable '%s'", name))
+		}
+	}
+
+	return okPacket()
+}
+
+func okPacket() []byte {
+	// Header, no affected rows or insert id, autocommit status, no warnings
+	return []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}
+}
+
+func errPacket(code uint16, message string) []byte {
+	packet := binary.LittleEndian.AppendUint16([]byte{0xff}, code)
+	packet = append(packet, "#HY000"...)
+	return append(packet, message...)
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	packet := make([]byte, length)
+	_, err := io.ReadFull(r, packet)
+	return header[3], packet, err
+}
+
+func writePacket(w io.Writer, seq byte, packet []byte) error {
+	header := []byte{byte(len(packet)), byte(len(packet) >> 8), byte(len(packet) >> 16), seq}
+	_, err := w.Write(append(header, packet...))
+	return err
+}
@@ -0,0 +1,103 @@
+package panes_test
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jdkingsbury/americano/internal/drivers"
+	"github.com/jdkingsbury/americano/internal/tui/panes"
+	"github.com/jdkingsbury/americano/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Creates a tree for the database and loads it like the layout does
+func newLoadedTree(db drivers.Database) *panes.DBTreeModel {
+	tree := panes.NewDBTreeModel(db)
+	tree.Update(tree.Init()())
+	return tree
+}
+
+// Presses the keys in order, table schemas are loaded before the next key
+func pressTreeKeys(tree *panes.DBTreeModel, keys ...tea.KeyMsg) tea.Cmd {
+	var cmd tea.Cmd
+	for _, key := range keys {
+		_, cmd = tree.Update(key)
+		if cmd == nil {
+			continue
+		}
+		if loaded, ok := cmd().(panes.TableSchemaLoadedMsg); ok {
+			tree.Update(loaded)
+		}
+	}
+	return cmd
+}
+
+var (
+	treeEnter = tea.KeyMsg{Type: tea.KeyEnter}
+	treeDown  = tea.KeyMsg{Type: tea.KeyDown}
+)
+
+func TestDBTree_LoadsInTheBackground(t *testing.T) {
+	db := &tests.MockDatabase{}
+	tree := panes.NewDBTreeModel(db)
+	assert.Contains(t, tree.View(), "Loading...")
+
+	// A tree loaded for another connection is dropped
+	other := panes.NewDBTreeModel(&tests.MockDatabase{})
+	tree.Update(other.Init()())
+	assert.Contains(t, tree.View(), "Loading...")
+
+	tree.Update(tree.Init()())
+	pressTreeKeys(tree, treeEnter)
+	assert.NotContains(t, tree.View(), "Loading...")
+	assert.Contains(t, tree.View(), "Tables")
+}
+
+func TestDBTree_LoadsTableSchemaWhenOpened(t *testing.T) {
+	tree := newLoadedTree(&tests.MockDatabase{})
+
+	// Open the database, Tables and mock_table
+	cmd := pressTreeKeys(tree, treeEnter, treeDown, treeEnter, treeDown)
+	assert.Nil(t, cmd)
+
+	_, cmd = tree.Update(treeEnter)
+	require.NotNil(t, cmd)
+	assert.Contains(t, tree.View(), "Loading...")
+
+	tree.Update(cmd())
+	assert.Contains(t, tree.View(), "Columns")
+	assert.NotContains(t, tree.View(), "Loading...")
+
+	pressTreeKeys(tree, treeDown, treeDown, treeEnter)
+	assert.Contains(t, tree.View(), "id INTEGER PK NOT NULL")
+}
+
+func TestDBTree_InsertsQueryOfSelectedTable(t *testing.T) {
+	tree := newLoadedTree(&tests.MockDatabase{Tables: []drivers.TableName{{Name: "authors"}, {Name: "books"}}})
+
+	// Open the second table and select its list query, which has the same title as the first
+	cmd := pressTreeKeys(tree, treeEnter, treeDown, treeEnter, treeDown, treeDown, treeEnter, treeDown, treeEnter)
+	require.NotNil(t, cmd)
+
+	msg, ok := cmd().(panes.InsertQueryMsg)
+	require.True(t, ok)
+	assert.Equal(t, `SELECT * FROM "books";`, msg.Query)
+}
+
+func TestDBTree_GroupsTablesBySchema(t *testing.T) {
+	tree := newLoadedTree(&tests.MockDatabase{Tables: []drivers.TableName{{Name: "orders"}, {Schema: "archive", Name: "orders"}}})
+
+	// Open the database, Tables and the archive schema, then list archive.orders
+	pressTreeKeys(tree, treeEnter, treeDown, treeEnter, treeDown, treeDown)
+	view := tree.View()
+	assert.Contains(t, view, "default")
+	assert.Contains(t, view, "archive")
+
+	cmd := pressTreeKeys(tree, treeEnter, treeDown, treeEnter, treeDown, treeEnter)
+	require.NotNil(t, cmd)
+
+	msg, ok := cmd().(panes.InsertQueryMsg)
+	require.True(t, ok)
+	assert.Equal(t, `SELECT * FROM "archive"."orders";`, msg.Query)
+}
@@ -6,6 +6,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/jdkingsbury/americano/internal/drivers"
 	"github.com/jdkingsbury/americano/internal/tui/panes"
+	"github.com/jdkingsbury/americano/msgtypes"
 	"github.com/jdkingsbury/americano/tests"
 	"github.com/stretchr/testify/assert"
 )
@@ -31,14 +32,21 @@ func TestEditorPane_ExecuteQuery(t *testing.T) {
 	editorModel, cmd = editor.Update(keyMsg)
 	editor = editorModel.(*panes.EditorPaneModel)
 
+	assert.True(t, editor.Running(), "expected query to be running")
+
 	// Execute the command (which should run the query)
-	var msg tea.Msg
-	if cmd != nil {
-		msg = cmd() // Capture the result message from cmd
+	var queryResult drivers.QueryResultMsg
+	var started, ok bool
+	for _, msg := range runCmd(cmd) {
+		switch msg := msg.(type) {
+		case panes.QueryStartedMsg:
+			started = true
+		case drivers.QueryResultMsg:
+			queryResult, ok = msg, true
+		}
 	}
 
-	// Type assert the result message to drivers.QueryResultMsg
-	queryResult, ok := msg.(drivers.QueryResultMsg)
+	assert.True(t, started, "expected QueryStartedMsg")
 	assert.True(t, ok, "expected QueryResultMsg, got something else")
 	assert.Nil(t, queryResult.Error, "expected no error in query result")
 	assert.Equal(t, []string{"id", "name"}, queryResult.Columns, "unexpected columns")
@@ -46,4 +54,183 @@ func TestEditorPane_ExecuteQuery(t *testing.T) {
 
 	// Check if the query is executed as expected
 	assert.Equal(t, "SELECT * FROM users;", mockDB.ExecutedQuery)
+
+	// Query result marks the editor as idle again
+	editor.Update(queryResult)
+	assert.False(t, editor.Running())
+}
+
+func TestEditorPane_CancelQuery(t *testing.T) {
+	mockDB := &tests.MockDatabase{Block: true}
+	editor := panes.NewEditorPane(80, 20, mockDB)
+
+	editor.Update(panes.InsertQueryMsg{Query: "SELECT * FROM big_table;"})
+	_, cmd := editor.Update(tea.KeyMsg{Type: tea.KeyCtrlE})
+
+	// Cancel once the query is in flight
+	done := make(chan []tea.Msg)
+	go func() { done <- runCmd(cmd) }()
+	editor.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+
+	var queryResult drivers.QueryResultMsg
+	for _, msg := range <-done {
+		if result, ok := msg.(drivers.QueryResultMsg); ok {
+			queryResult = result
+		}
+	}
+
+	assert.ErrorIs(t, queryResult.Error, drivers.ErrQueryCancelled)
+}
+
+// Runs a command and any batched commands, collecting the resulting messages
+func runCmd(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		var msgs []tea.Msg
+		for _, c := range batch {
+			msgs = append(msgs, runCmd(c)...)
+		}
+		return msgs
+	}
+
+	return []tea.Msg{msg}
+}
+
+func TestEditorPane_ManualCommit(t *testing.T) {
+	mockDB := &tests.MockDatabase{}
+	editor := panes.NewEditorPane(80, 20, mockDB)
+
+	editor.Update(tea.KeyMsg{Type: tea.KeyCtrlX})
+	assert.True(t, editor.ManualCommit())
+
+	// The first statement opens the transaction
+	editor.Update(panes.InsertQueryMsg{Query: "DELETE FROM users;"})
+	_, cmd := editor.Update(tea.KeyMsg{Type: tea.KeyCtrlE})
+	for _, msg := range runCmd(cmd) {
+		editor.Update(msg)
+	}
+	assert.True(t, editor.InTransaction())
+
+	// Manual commit mode can't be left with the transaction open
+	_, cmd = editor.Update(tea.KeyMsg{Type: tea.KeyCtrlX})
+	assert.True(t, editor.ManualCommit())
+	assert.IsType(t, msgtypes.ErrMsg{}, cmd())
+
+	_, cmd = editor.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	ended, ok := cmd().(panes.TransactionEndedMsg)
+	assert.True(t, ok)
+	assert.True(t, ended.Committed)
+	assert.True(t, mockDB.Committed)
+
+	_, cmd = editor.Update(ended)
+	assert.Equal(t, msgtypes.NewNotificationMsg("Transaction committed"), cmd())
+	assert.False(t, editor.InTransaction())
+}
+
+func TestEditorPane_RollbackWithoutTransaction(t *testing.T) {
+	mockDB := &tests.MockDatabase{}
+	editor := panes.NewEditorPane(80, 20, mockDB)
+
+	_, cmd := editor.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	assert.Equal(t, msgtypes.NewNotificationMsg("No transaction is open"), cmd())
+	assert.False(t, mockDB.RolledBack)
+}
+
+func TestEditorPane_PromptsForPlaceholderValues(t *testing.T) {
+	mockDB := &tests.MockDatabase{}
+	editor := panes.NewEditorPane(80, 20, mockDB)
+
+	query := "SELECT * FROM users WHERE id = :id;"
+	editor.Update(panes.InsertQueryMsg{Query: query})
+	editor.Update(tea.KeyMsg{Type: tea.KeyCtrlE})
+
+	assert.True(t, editor.ParamFormOpen())
+	assert.False(t, editor.Running(), "query waits for its values")
+	assert.Contains(t, editor.View(), ":id")
+
+	editor.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("7")})
+	_, cmd := editor.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	submit, ok := cmd().(panes.SubmitParamsMsg)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{":id": "7"}, submit.Values)
+
+	_, cmd = editor.Update(submit)
+	for _, msg := range runCmd(cmd) {
+		editor.Update(msg)
+	}
+	assert.False(t, editor.ParamFormOpen())
+	assert.Equal(t, query, mockDB.ExecutedQuery)
+	assert.Equal(t, map[string]any{":id": int64(7)}, mockDB.ExecutedArgs)
+
+	// The last values are filled in the next time the query runs
+	editor.Update(tea.KeyMsg{Type: tea.KeyCtrlE})
+	_, cmd = editor.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, map[string]string{":id": "7"}, cmd().(panes.SubmitParamsMsg).Values)
+}
+
+func TestEditorPane_ExplainQuery(t *testing.T) {
+	mockDB := &tests.MockDatabase{
+		QueryPlan: drivers.QueryPlanMsg{Nodes: []drivers.PlanNode{{ID: 2, Detail: "SCAN users", Warning: drivers.PlanFullTableScan}}},
+	}
+	editor := panes.NewEditorPane(80, 20, mockDB)
+
+	query := "SELECT * FROM users WHERE name = ?;"
+	editor.Update(panes.InsertQueryMsg{Query: query})
+	editor.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	assert.True(t, editor.ParamFormOpen(), "placeholders are asked for before explaining")
+
+	editor.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("'ada'")})
+	_, cmd := editor.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	_, cmd = editor.Update(cmd())
+	assert.True(t, editor.Running())
+
+	var plan drivers.QueryPlanMsg
+	for _, msg := range runCmd(cmd) {
+		if msg, ok := msg.(drivers.QueryPlanMsg); ok {
+			plan = msg
+		}
+	}
+
+	// The query is explained rather than run
+	assert.Equal(t, query, mockDB.ExplainedQuery)
+	assert.Empty(t, mockDB.ExecutedQuery)
+	assert.Equal(t, map[string]any{"?1": "ada"}, mockDB.ExecutedArgs)
+	assert.Len(t, plan.Nodes, 1)
+
+	editor.Update(plan)
+	assert.False(t, editor.Running())
+
+	// Scripts can't be explained as a whole
+	editor.Update(panes.InsertQueryMsg{Query: "SELECT 1; SELECT 2;"})
+	_, cmd = editor.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	errMsg, ok := cmd().(msgtypes.ErrMsg)
+	assert.True(t, ok)
+	assert.Contains(t, errMsg.Err.Error(), "single statement")
+
+	// ctrl+p is left to the textarea for moving up a line
+	editor.Update(panes.InsertQueryMsg{Query: "SELECT 1;"})
+	editor.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	assert.False(t, editor.Running())
+}
+
+func TestEditorPane_HidesKeysTheDriverLacks(t *testing.T) {
+	explainKeyEnabled := func(editor *panes.EditorPaneModel) bool {
+		for _, binding := range editor.KeyMap() {
+			if binding.Help().Key == "ctrl+g" {
+				return binding.Enabled()
+			}
+		}
+		return false
+	}
+
+	editor := panes.NewEditorPane(80, 20, &tests.MockDatabase{})
+	assert.True(t, explainKeyEnabled(editor))
+
+	// Only the methods of drivers.Database, so it can't explain queries
+	editor.SetDatabase(struct{ drivers.Database }{&tests.MockDatabase{}})
+	assert.False(t, explainKeyEnabled(editor))
 }
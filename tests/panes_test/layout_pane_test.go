@@ -1,10 +1,18 @@
 package panes_test
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jdkingsbury/americano/internal/config"
+	"github.com/jdkingsbury/americano/internal/drivers"
 	"github.com/jdkingsbury/americano/internal/tui/panes"
+	"github.com/jdkingsbury/americano/tests"
 )
 
 func TestLayoutModel_PaneSwitching(t *testing.T) {
@@ -39,3 +47,334 @@ func TestLayoutModel_HandleInsertQueryMsg(t *testing.T) {
 		t.Errorf("expected query to be 'SELECT * FROM users', got %s", editorPane.Query())
 	}
 }
+
+func TestLayoutModel_ConnectSharesOneSession(t *testing.T) {
+	layout := panes.NewLayoutModel()
+	layout.Update(tea.WindowSizeMsg{Width: 160, Height: 50})
+	defer layout.Close()
+
+	path := filepath.Join(t.TempDir(), "shared.db")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	_, cmd := layout.Update(panes.ConnectMsg{Name: "shared", URL: "sqlite:///" + path})
+	if !strings.Contains(layout.View(), "[shared: connecting]") {
+		t.Errorf("expected footer to show the connection in progress")
+	}
+
+	stateMsg, ok := cmd().(panes.ConnectionStateMsg)
+	if !ok || stateMsg.State != drivers.Connected {
+		t.Fatalf("expected a connected state message, got %#v", stateMsg)
+	}
+	layout.Update(stateMsg)
+
+	if !strings.Contains(layout.View(), "[shared: connected]") {
+		t.Errorf("expected footer to show the connection")
+	}
+	if layout.Session().Database() != stateMsg.DB {
+		t.Errorf("expected the session to own the connected database")
+	}
+
+	// Selecting another connection closes the shared one
+	layout.Update(panes.ConnectMsg{Name: "other", URL: "sqlite:///" + path})
+	if layout.Session().Database() != nil {
+		t.Errorf("expected the previous connection to be closed")
+	}
+	if err := stateMsg.DB.(*drivers.SQLite).Connection.Ping(); err == nil {
+		t.Errorf("expected the previous connection to be closed")
+	}
+}
+
+func TestLayoutModel_ReadOnlyConnectionShowsBadge(t *testing.T) {
+	layout := panes.NewLayoutModel()
+	layout.Update(tea.WindowSizeMsg{Width: 160, Height: 50})
+	defer layout.Close()
+
+	path := tests.NewSQLiteFile(t, "snapshot.db", "CREATE TABLE users (id INTEGER PRIMARY KEY);")
+
+	_, cmd := layout.Update(panes.ConnectMsg{Name: "snapshot", URL: "sqlite://" + path, Limits: drivers.Limits{ReadOnly: true}})
+	stateMsg, ok := cmd().(panes.ConnectionStateMsg)
+	if !ok || stateMsg.State != drivers.Connected {
+		t.Fatalf("expected a connected state message, got %#v", stateMsg)
+	}
+	layout.Update(stateMsg)
+
+	// Every pane shows the badge in its top border
+	if count := strings.Count(layout.View(), " RO "); count != 3 {
+		t.Errorf("expected the read-only badge on all 3 panes, found %d in '%s'", count, layout.View())
+	}
+
+	result := stateMsg.DB.ExecuteQuery(context.Background(), "DELETE FROM users;")
+	if !errors.Is(result.Error, drivers.ErrReadOnly) {
+		t.Errorf("expected the write to be rejected, got %v", result.Error)
+	}
+
+	// The badge goes away with the read-only connection
+	layout.Update(panes.ConnectMsg{Name: "other", URL: "sqlite://" + path})
+	if strings.Contains(layout.View(), " RO ") {
+		t.Errorf("expected the read-only badge to be hidden")
+	}
+}
+
+func TestLayoutModel_QuitWithOpenTransactionAsksFirst(t *testing.T) {
+	layout := panes.NewLayoutModel()
+	mockDB := &tests.MockDatabase{TxOpen: true}
+	layout.Panes()[panes.EditorPane].(*panes.EditorPaneModel).SetDatabase(mockDB)
+
+	quit := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Q")}
+	_, cmd := layout.Update(quit)
+	if cmd != nil || !layout.ConfirmPending() {
+		t.Fatalf("expected quitting to wait for confirmation")
+	}
+	if !strings.Contains(layout.View(), "TX OPEN") {
+		t.Errorf("expected footer to show the open transaction")
+	}
+
+	// Declining keeps the app running
+	layout.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if layout.ConfirmPending() {
+		t.Errorf("expected the confirmation to be dismissed")
+	}
+
+	layout.Update(quit)
+	_, cmd = layout.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if cmd == nil {
+		t.Fatalf("expected quit command")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Errorf("expected tea.QuitMsg")
+	}
+}
+
+func TestLayoutModel_AttachDatabaseFromTree(t *testing.T) {
+	layout := panes.NewLayoutModel()
+	layout.Update(tea.WindowSizeMsg{Width: 160, Height: 50})
+
+	db := tests.NewSQLiteDatabase(t)
+	db.ExecuteQuery(context.Background(), "CREATE TABLE customers (id INTEGER);")
+	archive := tests.NewSQLiteFile(t, "archive.db", "CREATE TABLE orders (id INTEGER);")
+
+	layout.Update(panes.ConnectionStateMsg{ID: layout.Session().ID(), Name: "live", State: drivers.Connected, DB: db})
+	layout.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	if layout.CurrentPane() != panes.SideBarPane {
+		t.Fatalf("expected the sidebar to be focused")
+	}
+
+	// Typing into the form must not switch panes or views
+	layout.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	layout.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(archive)})
+	if !strings.Contains(layout.View(), "Attach Database") {
+		t.Fatalf("expected the attach form to be open")
+	}
+
+	_, cmd := layout.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	submit, ok := cmd().(panes.SubmitAttachMsg)
+	if !ok || submit.Path != archive {
+		t.Fatalf("expected the form to submit the typed path, got %#v", submit)
+	}
+
+	_, cmd = layout.Update(submit)
+	changed, ok := cmd().(panes.SchemaChangedMsg)
+	if !ok || changed.Error != nil {
+		t.Fatalf("expected the database to attach, got %#v", changed)
+	}
+
+	// The tree reloads in the background
+	_, cmd = layout.Update(changed)
+	for _, msg := range runCmd(cmd) {
+		layout.Update(msg)
+	}
+	sideBar := layout.Panes()[panes.SideBarPane].View()
+	if !strings.Contains(sideBar, "archive") || !strings.Contains(sideBar, "main") {
+		t.Errorf("expected the tree to group tables by schema, got\n%s", sideBar)
+	}
+	if len(db.Attachments()) != 1 {
+		t.Errorf("expected one attached database, got %d", len(db.Attachments()))
+	}
+}
+
+func TestLayoutModel_DeleteConnectionAsksFirst(t *testing.T) {
+	layout := panes.NewLayoutModel()
+	layout.Update(tea.WindowSizeMsg{Width: 160, Height: 50})
+
+	sidebar := layout.Panes()[panes.SideBarPane].(*panes.SideBarPaneModel)
+	sidebar.Update(panes.SubmitFormMsg{Name: "old", URL: "sqlite://./old.db"})
+	sidebar.Update(panes.SubmitFormMsg{Name: "dead", URL: "sqlite://./dead.db"})
+
+	// Focus the sidebar and select the last connection
+	layout.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	layout.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+
+	press := func(keys string) {
+		_, cmd := layout.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(keys)})
+		for _, msg := range runCmd(cmd) {
+			_, cmd = layout.Update(msg)
+			runCmd(cmd)
+		}
+	}
+
+	press("x")
+	if !layout.ConfirmPending() || !strings.Contains(layout.View(), "Delete connection dead? (y/n)") {
+		t.Fatalf("expected a confirmation prompt, got '%s'", layout.View())
+	}
+
+	press("n")
+	if len(sidebar.Connections()) != 2 {
+		t.Errorf("expected the connection to be kept, got %d connections", len(sidebar.Connections()))
+	}
+
+	press("x")
+	press("y")
+	if len(sidebar.Connections()) != 1 || sidebar.Connections()[0].Name != "old" {
+		t.Errorf("expected only the old connection to be left, got %+v", sidebar.Connections())
+	}
+}
+
+func TestLayoutModel_UnlocksSecretsBeforeConnecting(t *testing.T) {
+	layout := panes.NewLayoutModel()
+	layout.Update(tea.WindowSizeMsg{Width: 160, Height: 50})
+	defer layout.Close()
+	t.Cleanup(func() { drivers.SetSecretLookup(nil) })
+
+	secretsPath := filepath.Join(t.TempDir(), "secrets.json")
+	layout.SetSecretsPath(secretsPath)
+
+	secrets, err := config.OpenSecrets(secretsPath, "open sesame")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := secrets.Set("app", tests.NewSQLiteFile(t, "app.db")); err != nil {
+		t.Fatal(err)
+	}
+	if err := secrets.Set("unused", "left behind"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Secrets that no saved connection references are deleted once unlocked
+	sidebar := layout.Panes()[panes.SideBarPane].(*panes.SideBarPaneModel)
+	sidebar.Update(panes.SubmitFormMsg{Name: "app", URL: "sqlite://${secret:app}"})
+
+	layout.Update(panes.ConnectMsg{Name: "app", URL: "sqlite://${secret:app}"})
+	if !layout.PassphraseFormOpen() || !strings.Contains(layout.View(), "Unlock Secrets") {
+		t.Fatalf("expected the passphrase to be asked for, got '%s'", layout.View())
+	}
+
+	// Runs the messages of a passphrase until the connection is opened
+	unlock := func(passphrase string) []tea.Msg {
+		layout.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(passphrase)})
+		_, cmd := layout.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		for _, msg := range runCmd(cmd) {
+			_, cmd = layout.Update(msg)
+			for _, msg := range runCmd(cmd) {
+				_, cmd = layout.Update(msg)
+			}
+		}
+		return runCmd(cmd)
+	}
+
+	unlock("wrong")
+	if !layout.PassphraseFormOpen() || !strings.Contains(layout.View(), "wrong passphrase") {
+		t.Fatalf("expected the passphrase to be asked for again, got '%s'", layout.View())
+	}
+
+	msgs := unlock("open sesame")
+	if layout.PassphraseFormOpen() {
+		t.Fatalf("expected the passphrase form to close")
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("expected the connection to open, got %#v", msgs)
+	}
+	if reopened, err := config.OpenSecrets(secretsPath, "open sesame"); err != nil || len(reopened.Names()) != 1 {
+		t.Errorf("expected only the referenced secret to be kept, got %v %v", reopened, err)
+	}
+	stateMsg, ok := msgs[0].(panes.ConnectionStateMsg)
+	if !ok || stateMsg.State != drivers.Connected {
+		t.Fatalf("expected a connected state message, got %#v", msgs[0])
+	}
+	layout.Update(stateMsg)
+
+	// The secrets stay unlocked for the session
+	_, cmd := layout.Update(panes.ConnectMsg{Name: "again", URL: "sqlite://${secret:app}"})
+	if layout.PassphraseFormOpen() || cmd == nil {
+		t.Errorf("expected to connect without asking again")
+	}
+}
+
+func TestLayoutModel_SecretsOfSameNamedConnectionsDontCollide(t *testing.T) {
+	layout := panes.NewLayoutModel()
+	layout.Update(tea.WindowSizeMsg{Width: 160, Height: 50})
+	defer layout.Close()
+	t.Cleanup(func() { drivers.SetSecretLookup(nil) })
+
+	secretsPath := filepath.Join(t.TempDir(), "secrets.json")
+	layout.SetSecretsPath(secretsPath)
+
+	// Saves a connection named prod through the form, storing its password as a secret
+	addProd := func(password string) {
+		form := panes.NewDBFormModel()
+		typeInto(form, "prod")
+		nextInput(form)
+		typeInto(form, "postgres://")
+		form.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+		typeInto(form, "db")
+		for range 3 {
+			nextInput(form)
+		}
+		typeInto(form, password)
+		form.Update(tea.KeyMsg{Type: tea.KeyCtrlK})
+		for range 5 {
+			nextInput(form)
+		}
+
+		_, cmd := form.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		_, cmd = layout.Update(cmd())
+		for _, msg := range runCmd(cmd) {
+			_, cmd = layout.Update(msg)
+		}
+		if layout.PassphraseFormOpen() {
+			layout.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("open sesame")})
+			layout.Update(tea.KeyMsg{Type: tea.KeyTab})
+			layout.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("open sesame")})
+			_, cmd = layout.Update(tea.KeyMsg{Type: tea.KeyEnter})
+			for _, msg := range runCmd(cmd) {
+				_, cmd = layout.Update(msg)
+				for _, msg := range runCmd(cmd) {
+					layout.Update(msg)
+				}
+			}
+		}
+	}
+
+	addProd("first")
+	addProd("second")
+
+	sidebar := layout.Panes()[panes.SideBarPane].(*panes.SideBarPaneModel)
+	connections := sidebar.Connections()
+	if len(connections) != 2 || connections[0].URL == connections[1].URL {
+		t.Fatalf("expected two connections with their own secret, got %+v", connections)
+	}
+
+	secrets, err := config.OpenSecrets(secretsPath, "open sesame")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, password := range []string{"first", "second"} {
+		name := drivers.SecretNames(connections[i].URL)[0]
+		if value, _ := secrets.Get(name); value != password {
+			t.Errorf("expected %s to keep password %q, got %q", connections[i].Name, password, value)
+		}
+	}
+
+	// Deleting a connection deletes its secret. The list starts with the add button.
+	layout.Update(panes.DeleteConnectionMsg{Index: 1})
+	secrets, err = config.OpenSecrets(secretsPath, "open sesame")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := secrets.Names(); len(names) != 1 || names[0] != drivers.SecretNames(connections[1].URL)[0] {
+		t.Errorf("expected only the secret of the remaining connection, got %v", names)
+	}
+}
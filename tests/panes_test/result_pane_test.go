@@ -1,13 +1,17 @@
 package panes_test
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/jdkingsbury/americano/internal/drivers"
 	"github.com/jdkingsbury/americano/internal/tui/panes"
 	"github.com/jdkingsbury/americano/msgtypes"
+	"github.com/jdkingsbury/americano/tests"
 )
 
 func TestResultPane_UpdateTable(t *testing.T) {
@@ -81,3 +85,201 @@ func TestResultPane_DisplayNotification(t *testing.T) {
 		t.Errorf("Expected notification message '%s' to be displayed, but got '%s'", expectedNotification, output)
 	}
 }
+
+func TestResultPane_DisplayRowsAffected(t *testing.T) {
+	resultPane := panes.NewResultPaneModel(80, 20)
+
+	queryMsg := drivers.QueryResultMsg{IsExec: true, RowsAffected: 3, LastInsertId: 7}
+	model, _ := resultPane.HandleMsg(queryMsg)
+	resultPane = model.(*panes.ResultPaneModel)
+
+	expectedSummary := "Query OK, 3 rows affected, last insert id 7"
+	if resultPane.Summary() != expectedSummary {
+		t.Errorf("Expected summary '%s', but got '%s'", expectedSummary, resultPane.Summary())
+	}
+
+	if !strings.Contains(resultPane.View(), expectedSummary) {
+		t.Errorf("Expected summary '%s' to be displayed, but got '%s'", expectedSummary, resultPane.View())
+	}
+}
+
+func TestResultPane_FlipScriptResults(t *testing.T) {
+	resultPane := panes.NewResultPaneModel(80, 20)
+
+	scriptMsg := drivers.ScriptResultMsg{
+		Results: []drivers.QueryResultMsg{
+			{Query: "UPDATE users SET name = 'a';", IsExec: true, RowsAffected: 2},
+			{Query: "SELECT id FROM users;", Columns: []string{"id"}, Rows: [][]string{{"1"}, {"2"}}},
+		},
+		Total: 2,
+	}
+	model, _ := resultPane.HandleMsg(scriptMsg)
+	resultPane = model.(*panes.ResultPaneModel)
+
+	// The last result is shown when every statement succeeds
+	if resultPane.ResultIndex() != 1 {
+		t.Errorf("Expected result index 1, but got %d", resultPane.ResultIndex())
+	}
+	if !strings.Contains(resultPane.View(), "Statement 2/2") {
+		t.Errorf("Expected statement header to be displayed, but got '%s'", resultPane.View())
+	}
+
+	resultPane.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("[")})
+
+	if resultPane.ResultIndex() != 0 {
+		t.Errorf("Expected result index 0, but got %d", resultPane.ResultIndex())
+	}
+	if !strings.Contains(resultPane.View(), "Query OK, 2 rows affected") {
+		t.Errorf("Expected first statement summary to be displayed, but got '%s'", resultPane.View())
+	}
+}
+
+func TestResultPane_TruncatesScriptHeaderByWidth(t *testing.T) {
+	resultPane := panes.NewResultPaneModel(80, 20)
+
+	// Wide characters that a byte cut would split
+	query := "SELECT 12, '" + strings.Repeat("日本", 40) + "';"
+	scriptMsg := drivers.ScriptResultMsg{
+		Results: []drivers.QueryResultMsg{
+			{Query: "SELECT 1;", Columns: []string{"1"}, Rows: [][]string{{"1"}}},
+			{Query: query, Columns: []string{"name"}, Rows: [][]string{{"a"}}},
+		},
+		Total: 2,
+	}
+	model, _ := resultPane.HandleMsg(scriptMsg)
+	resultPane = model.(*panes.ResultPaneModel)
+
+	view := resultPane.View()
+	if !utf8.ValidString(view) {
+		t.Errorf("Expected the header to be cut between characters, but got '%s'", view)
+	}
+	if !strings.Contains(view, "Statement 2/2: SELECT 12, '日本日本") || !strings.Contains(view, "…") {
+		t.Errorf("Expected a truncated statement header, but got '%s'", view)
+	}
+}
+
+func TestResultPane_FormatsTypedCells(t *testing.T) {
+	resultPane := panes.NewResultPaneModel(80, 20)
+
+	queryMsg := drivers.QueryResultMsg{
+		Columns: []string{"id", "name"},
+		Rows:    [][]string{{"7", "NULL"}},
+		Cells: [][]drivers.Cell{
+			{{Kind: drivers.NumberCell, Value: int64(7)}, {Kind: drivers.NullCell}},
+		},
+	}
+	model, _ := resultPane.HandleMsg(queryMsg)
+	resultPane = model.(*panes.ResultPaneModel)
+
+	row := resultPane.Table().Rows()[0]
+	width := resultPane.Table().Columns()[0].Width
+
+	if len(row[0]) != width || !strings.HasSuffix(row[0], "7") {
+		t.Errorf("Expected number to be right aligned to width %d, but got '%s'", width, row[0])
+	}
+	if row[1] != "<null>" {
+		t.Errorf("Expected NULL to be displayed as '<null>', but got '%s'", row[1])
+	}
+}
+
+func TestResultPane_FetchesMoreRowsOnScroll(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	queryMsg := db.ExecuteQuery(context.Background(), "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 300) SELECT x FROM c;")
+
+	resultPane := panes.NewResultPaneModel(80, 20)
+	model, _ := resultPane.HandleMsg(queryMsg)
+	resultPane = model.(*panes.ResultPaneModel)
+
+	if !strings.Contains(resultPane.View(), "more available") {
+		t.Errorf("Expected more rows indicator to be displayed, but got '%s'", resultPane.View())
+	}
+
+	// Jump to the last fetched row to trigger the next page
+	_, cmd := resultPane.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	for _, msg := range runCmd(cmd) {
+		if fetched, ok := msg.(panes.RowsFetchedMsg); ok {
+			resultPane.HandleMsg(fetched)
+		}
+	}
+
+	if len(resultPane.Table().Rows()) != 300 {
+		t.Errorf("Expected 300 rows after fetching, but got %d rows", len(resultPane.Table().Rows()))
+	}
+	if resultPane.HasMoreRows() {
+		t.Errorf("Expected no more rows after the cursor is exhausted")
+	}
+}
+
+func TestResultPane_StopsFetchingAtMemoryCap(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	queryMsg := db.ExecuteQuery(context.Background(), "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000) SELECT x FROM c;")
+
+	resultPane := panes.NewResultPaneModel(80, 20)
+	resultPane.SetMemoryCap(1)
+	resultPane.HandleMsg(queryMsg)
+
+	_, cmd := resultPane.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	for _, msg := range runCmd(cmd) {
+		if fetched, ok := msg.(panes.RowsFetchedMsg); ok {
+			resultPane.HandleMsg(fetched)
+		}
+	}
+
+	// A second scroll does not fetch past the cap
+	_, cmd = resultPane.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	for _, msg := range runCmd(cmd) {
+		if _, ok := msg.(panes.RowsFetchedMsg); ok {
+			t.Errorf("Expected no fetch after the memory cap was reached")
+		}
+	}
+
+	if len(resultPane.Table().Rows()) != 400 {
+		t.Errorf("Expected 400 rows, but got %d rows", len(resultPane.Table().Rows()))
+	}
+	if !strings.Contains(resultPane.View(), "memory cap") {
+		t.Errorf("Expected memory cap indicator to be displayed, but got '%s'", resultPane.View())
+	}
+}
+
+func TestResultPane_ShowsRowLimitTruncation(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	db.SetLimits(drivers.Limits{MaxRows: 10})
+	queryMsg := db.ExecuteQuery(context.Background(), "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 100) SELECT x FROM c;")
+
+	resultPane := panes.NewResultPaneModel(120, 40)
+	resultPane.HandleMsg(queryMsg)
+
+	if !strings.Contains(resultPane.View(), "showing the first 10 rows, the connection's row limit cut off the rest") {
+		t.Errorf("Expected row limit indicator to be displayed, but got '%s'", resultPane.View())
+	}
+}
+
+func TestResultPane_ShowsQueryPlanTree(t *testing.T) {
+	db := tests.NewSQLiteDatabase(t)
+	db.ExecuteQuery(context.Background(), "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);")
+	plan := db.ExplainQuery(context.Background(), "SELECT * FROM users WHERE id IN (SELECT id FROM users WHERE name > 'm') ORDER BY name;", nil)
+	if plan.Error != nil {
+		t.Fatalf("ExplainQuery returned an error: %v", plan.Error)
+	}
+
+	resultPane := panes.NewResultPaneModel(120, 60)
+	resultPane.Update(plan)
+
+	view := resultPane.View()
+	for _, expected := range []string{"QUERY PLAN (2 warnings)", "SCAN users (full table scan)", "USE TEMP B-TREE FOR ORDER BY (temp B-tree)", "└─ "} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected plan view to contain '%s', but got '%s'", expected, view)
+		}
+	}
+
+	// The subquery scan is nested below the step that uses it
+	if !strings.Contains(view, "│  └─ ⚠ SCAN users") {
+		t.Errorf("Expected nested plan steps, but got '%s'", view)
+	}
+
+	// The next query result replaces the plan
+	resultPane.Update(drivers.QueryResultMsg{Columns: []string{"id"}, Rows: [][]string{{"1"}}})
+	if resultPane.Plan() != nil {
+		t.Errorf("Expected the plan to be cleared, but got %+v", resultPane.Plan())
+	}
+}
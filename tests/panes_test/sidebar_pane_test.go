@@ -1,11 +1,16 @@
 package panes_test
 
 import (
+	"path/filepath"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jdkingsbury/americano/internal/config"
+	"github.com/jdkingsbury/americano/internal/drivers"
 	"github.com/jdkingsbury/americano/internal/tui/panes"
+	"github.com/jdkingsbury/americano/msgtypes"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSideBarPane_SwitchView(t *testing.T) {
@@ -29,3 +34,135 @@ func TestSideBarPane_SelectItem(t *testing.T) {
 
 	assert.True(t, sidebar.ShowInputForm())
 }
+
+func TestSideBarPane_SavesConnections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "americano", "config.json")
+
+	sidebar := panes.NewSideBarPane(80, 20)
+	require.NoError(t, sidebar.LoadConnections(path))
+
+	limits := drivers.Limits{MaxRows: 100, ReadOnly: true}
+	_, cmd := sidebar.Update(panes.SubmitFormMsg{Name: "snapshot", URL: "sqlite://./snapshot.db", Limits: limits})
+	for _, msg := range runCmd(cmd) {
+		_, failed := msg.(msgtypes.ErrMsg)
+		assert.False(t, failed, "saving reports no error: %v", msg)
+	}
+
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+	require.Len(t, cfg.Connections, 1)
+	assert.Equal(t, "snapshot", cfg.Connections[0].Name)
+
+	// The next start loads the saved connections
+	restarted := panes.NewSideBarPane(80, 20)
+	require.NoError(t, restarted.LoadConnections(path))
+	require.Len(t, restarted.Connections(), 1)
+	assert.Equal(t, "sqlite://./snapshot.db", restarted.Connections()[0].URL)
+	assert.Equal(t, limits, restarted.Connections()[0].Limits)
+}
+
+// Sidebar with saved connections a, b and c, saved to a config file
+func newSideBarWithConnections(t *testing.T) (*panes.SideBarPaneModel, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	cfg := config.Config{Connections: []config.Connection{
+		config.NewConnection("a", "sqlite://./a.db", drivers.Limits{}),
+		config.NewConnection("b", "sqlite://./b.db", drivers.Limits{MaxRows: 10}),
+		config.NewConnection("c", "sqlite://./c.db", drivers.Limits{}),
+	}}
+	require.NoError(t, config.Save(path, cfg))
+
+	sidebar := panes.NewSideBarPane(240, 60)
+	require.NoError(t, sidebar.LoadConnections(path))
+
+	return sidebar, path
+}
+
+func pressKey(sidebar *panes.SideBarPaneModel, keys string) tea.Cmd {
+	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(keys)}
+	if keys == "enter" {
+		msg = tea.KeyMsg{Type: tea.KeyEnter}
+	}
+
+	_, cmd := sidebar.Update(msg)
+	return cmd
+}
+
+func savedNames(t *testing.T, path string) []string {
+	t.Helper()
+
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+
+	var names []string
+	for _, connection := range cfg.Connections {
+		names = append(names, connection.Name)
+	}
+	return names
+}
+
+func TestSideBarPane_EditConnection(t *testing.T) {
+	sidebar, path := newSideBarWithConnections(t)
+
+	pressKey(sidebar, "j")
+	pressKey(sidebar, "j")
+	for _, msg := range runCmd(pressKey(sidebar, "e")) {
+		sidebar.Update(msg)
+	}
+
+	require.True(t, sidebar.ShowInputForm())
+	assert.Contains(t, sidebar.View(), "Edit Connection")
+	assert.Contains(t, sidebar.View(), "sqlite://./b.db")
+
+	// Fix the URL and submit the form
+	sidebar.Update(tea.KeyMsg{Type: tea.KeyTab})
+	sidebar.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	sidebar.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	pressKey(sidebar, "sqlite")
+	for range 3 {
+		sidebar.Update(tea.KeyMsg{Type: tea.KeyTab})
+	}
+	for _, msg := range runCmd(pressKey(sidebar, "enter")) {
+		sidebar.Update(msg)
+	}
+
+	assert.False(t, sidebar.ShowInputForm())
+	connections := sidebar.Connections()
+	require.Len(t, connections, 3)
+	assert.Equal(t, "b", connections[1].Name)
+	assert.Equal(t, "sqlite://./b.sqlite", connections[1].URL)
+	assert.Equal(t, drivers.Limits{MaxRows: 10}, connections[1].Limits)
+	assert.Equal(t, []string{"a", "b", "c"}, savedNames(t, path))
+
+	// The next add starts with an empty form
+	pressKey(sidebar, "g")
+	pressKey(sidebar, "enter")
+	assert.Contains(t, sidebar.View(), "Add Connection")
+	assert.NotContains(t, sidebar.View(), "sqlite://./b.sqlite")
+}
+
+func TestSideBarPane_DuplicateAndMoveConnections(t *testing.T) {
+	sidebar, path := newSideBarWithConnections(t)
+
+	// The copy is selected after duplicating
+	pressKey(sidebar, "j")
+	runCmd(pressKey(sidebar, "c"))
+	pressKey(sidebar, "k")
+	runCmd(pressKey(sidebar, "c"))
+	assert.Equal(t, []string{"a", "a (copy 2)", "a (copy)", "b", "c"}, savedNames(t, path))
+
+	// The moved connection stays selected
+	runCmd(pressKey(sidebar, "J"))
+	runCmd(pressKey(sidebar, "J"))
+	assert.Equal(t, []string{"a", "a (copy)", "b", "a (copy 2)", "c"}, savedNames(t, path))
+
+	runCmd(pressKey(sidebar, "K"))
+	assert.Equal(t, []string{"a", "a (copy)", "a (copy 2)", "b", "c"}, savedNames(t, path))
+
+	// Nothing moves above the Add Connection button
+	pressKey(sidebar, "g")
+	pressKey(sidebar, "j")
+	runCmd(pressKey(sidebar, "K"))
+	assert.Equal(t, "a", sidebar.Connections()[0].Name)
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdkingsbury/americano/internal/drivers"
+)
+
+// Creates an empty sqlite database file in a temp dir and connects to it
+func NewSQLiteDatabase(t *testing.T) *drivers.SQLite {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create database file: %v", err)
+	}
+	f.Close()
+
+	db := &drivers.SQLite{}
+	if err := db.Connect(context.Background(), "sqlite:///"+path); err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.CloseConnection() })
+
+	return db
+}
+
+// Creates a sqlite database file from the given statements and returns its path
+func NewSQLiteFile(t *testing.T, name string, statements ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	db := &drivers.SQLite{}
+	if err := db.Connect(context.Background(), "sqlite://"+path+"?create=true"); err != nil {
+		t.Fatalf("failed to create database file: %v", err)
+	}
+	defer db.CloseConnection()
+
+	for _, statement := range statements {
+		if result := db.ExecuteQuery(context.Background(), statement); result.Error != nil {
+			t.Fatalf("failed to run %q: %v", statement, result.Error)
+		}
+	}
+
+	return path
+}